@@ -0,0 +1,212 @@
+// Command messages_report lists the grammar rules each language of the
+// message catalogue has no explanation for.
+//
+// For every language it prints its coverage of the rule set, the rules it
+// lacks and the language in its fallback chain that explains them instead
+// (or English, the rule's own description), messages for rule IDs that no
+// longer exist, and messages using {corrected} for rules that only flag text.
+// Languages with messages in other explanation styles, such as code_mixed,
+// also get the coverage of each style.
+//
+//	go run ./cmd/messages_report -lang Hindi,Tamil
+//
+// With -strict it exits non-zero when anything is missing, so translations
+// can be gated in CI.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/messages"
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/rules"
+)
+
+// LanguageReport is what one language file lacks
+type LanguageReport struct {
+	Language string
+	Covered  int
+	Styles   map[string]int    // Rules covered in each other style
+	Missing  []string          // Rule IDs without a message of the language's own
+	Fallback map[string]string // Language used instead, by missing rule ID
+	Stale    []string          // Message keys that are not rule IDs
+	Problems []string          // Messages that cannot render for their rule
+}
+
+func main() {
+	rulesDir := flag.String("rules", "", "directory of rule files to check against instead of the built-in rules")
+	messagesDir := flag.String("messages", "", "directory of language files to check instead of the built-in catalogue")
+	languages := flag.String("lang", "", "comma-separated languages to report on, all when empty")
+	strict := flag.Bool("strict", false, "exit 1 when a language lacks a message or has a stale or broken one")
+	flag.Parse()
+
+	ruleSet := rules.Active()
+	if *rulesDir != "" {
+		loaded, err := rules.LoadDir(*rulesDir)
+		if err != nil {
+			log.Fatalf("Failed to load rules: %v", err)
+		}
+		ruleSet = loaded
+	}
+
+	catalogue := messages.Default()
+	if *messagesDir != "" {
+		loaded, err := messages.LoadDir(*messagesDir)
+		if err != nil {
+			log.Fatalf("Failed to load messages: %v", err)
+		}
+		catalogue = loaded
+	}
+
+	names := catalogue.Languages()
+	if *languages != "" {
+		names = strings.Split(*languages, ",")
+		for i, name := range names {
+			names[i] = strings.TrimSpace(name)
+			if catalogue.Language(names[i]) == nil {
+				log.Fatalf("No language file for %q", names[i])
+			}
+		}
+	}
+
+	reports := make([]LanguageReport, 0, len(names))
+	for _, name := range names {
+		reports = append(reports, check(catalogue, ruleSet, name))
+	}
+
+	fmt.Printf("Message catalogue: %s, %d rules\n", catalogue.Source, len(ruleSet.Rules))
+	incomplete := printReports(os.Stdout, reports, len(ruleSet.Rules))
+
+	if *strict && incomplete > 0 {
+		fmt.Printf("\n%d language(s) incomplete\n", incomplete)
+		os.Exit(1)
+	}
+}
+
+// check compares one language of the catalogue with the rule set
+func check(catalogue *messages.Catalogue, ruleSet *rules.RuleSet, name string) LanguageReport {
+	language := catalogue.Language(name)
+	report := LanguageReport{
+		Language: language.Name,
+		Missing:  make([]string, 0),
+		Fallback: make(map[string]string),
+		Styles:   make(map[string]int),
+		Stale:    make([]string, 0),
+		Problems: make([]string, 0),
+	}
+
+	for _, rule := range ruleSet.Rules {
+		text, ok := language.Messages[rule.ID]
+		if !ok {
+			report.Missing = append(report.Missing, rule.ID)
+			report.Fallback[rule.ID] = fallbackFor(catalogue, language, rule.ID)
+			continue
+		}
+		report.Covered++
+		report.Problems = append(report.Problems, placeholderProblems(rule, "", text)...)
+
+		for style, styled := range language.Styles {
+			if text, ok := styled[rule.ID]; ok {
+				report.Styles[style]++
+				report.Problems = append(report.Problems, placeholderProblems(rule, style, text)...)
+			}
+		}
+	}
+
+	for id := range language.Messages {
+		if ruleSet.Lookup(id) == nil {
+			report.Stale = append(report.Stale, id)
+		}
+	}
+	for style, styled := range language.Styles {
+		for id := range styled {
+			if ruleSet.Lookup(id) == nil {
+				report.Stale = append(report.Stale, style+"."+id)
+			}
+		}
+	}
+	sort.Strings(report.Stale)
+
+	return report
+}
+
+// placeholderProblems lists the placeholders a message uses that its rule
+// never fills
+func placeholderProblems(rule rules.GrammarRule, style, text string) []string {
+	if rule.Rewrites {
+		return nil
+	}
+	name := rule.ID
+	if style != "" {
+		name = style + "." + rule.ID
+	}
+
+	problems := make([]string, 0)
+	for _, placeholder := range messages.PlaceholdersIn(text) {
+		if placeholder == "corrected" || placeholder == "corrected_sentence" {
+			problems = append(problems, fmt.Sprintf("%s uses {%s} but the rule does not correct text", name, placeholder))
+		}
+	}
+	return problems
+}
+
+// fallbackFor returns the first language after language in its chain that
+// has a message for the rule, or English
+func fallbackFor(catalogue *messages.Catalogue, language *messages.Language, ruleID string) string {
+	for _, candidate := range catalogue.Chain(language.Name)[1:] {
+		if _, ok := candidate.Messages[ruleID]; ok {
+			return candidate.Name
+		}
+	}
+	return "English"
+}
+
+// printReports writes the reports and returns how many languages are incomplete
+func printReports(w io.Writer, reports []LanguageReport, total int) int {
+	incomplete := 0
+	for _, report := range reports {
+		coverage := 0.0
+		if total > 0 {
+			coverage = float64(report.Covered) / float64(total) * 100
+		}
+		fmt.Fprintf(w, "\n%s: %d/%d rules (%.0f%%)\n", report.Language, report.Covered, total, coverage)
+
+		styles := make([]string, 0, len(report.Styles))
+		for style := range report.Styles {
+			styles = append(styles, style)
+		}
+		sort.Strings(styles)
+		for _, style := range styles {
+			fmt.Fprintf(w, "  %s: %d/%d rules\n", style, report.Styles[style], total)
+		}
+
+		if len(report.Missing) > 0 {
+			fmt.Fprintf(w, "  Missing:\n")
+			for _, id := range report.Missing {
+				fmt.Fprintf(w, "    - %s (falls back to %s)\n", id, report.Fallback[id])
+			}
+		}
+		if len(report.Stale) > 0 {
+			fmt.Fprintf(w, "  Unknown rule IDs:\n")
+			for _, id := range report.Stale {
+				fmt.Fprintf(w, "    - %s\n", id)
+			}
+		}
+		if len(report.Problems) > 0 {
+			fmt.Fprintf(w, "  Problems:\n")
+			for _, problem := range report.Problems {
+				fmt.Fprintf(w, "    - %s\n", problem)
+			}
+		}
+
+		if len(report.Missing) > 0 || len(report.Stale) > 0 || len(report.Problems) > 0 {
+			incomplete++
+		}
+	}
+	return incomplete
+}
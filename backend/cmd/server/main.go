@@ -1,38 +1,93 @@
 package main
 
 import (
+	"context"
+	"crypto/subtle"
+	"errors"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	fiberws "github.com/gofiber/websocket/v2"
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 	
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/messages"
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/rules"
 	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/services"
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/supabase"
 	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/websocket"
 )
 
+// ruleFeedbackInterval is how often disputed rules are demoted or disabled
+const ruleFeedbackInterval = 10 * time.Minute
+
+// disputesPerMinute is how many disputes one address may send a minute
+const disputesPerMinute = 10
+
+// supportedLanguages are the native languages explanations are offered in
+var supportedLanguages = []string{
+	"Hindi", "Tamil", "Telugu", "Marathi",
+	"Punjabi", "Bengali", "Gujarati", "Kannada", "Malayalam",
+}
+
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		log.Printf("Warning: .env file not found")
 	}
 
+	// Load grammar rules from disk when configured, otherwise the built-in rules are used
+	if rulesDir := os.Getenv("RULES_DIR"); rulesDir != "" {
+		ruleSet, err := rules.LoadDir(rulesDir)
+		if err != nil {
+			log.Fatalf("Failed to load grammar rules: %v", err)
+		}
+		rules.SetActive(ruleSet)
+		log.Printf("Loaded %d grammar rules from %s", len(ruleSet.Rules), rulesDir)
+
+		// Pick up rule edits without a restart
+		go rules.NewWatcher(rulesDir, 2*time.Second).Run()
+	}
+
+	// Supabase is optional; without it feedback and translations are kept in memory
+	db := supabase.NewClient()
+	if db == nil {
+		log.Printf("Warning: SUPABASE_URL or SUPABASE_SERVICE_KEY not set, rule feedback, translations and preferences will not be saved")
+	}
+
 	// Initialize services
 	llmRouter := services.NewLLMRouter()
-	grammarDetector := services.NewGrammarDetector(llmRouter)
+	translationCache := services.NewTranslationCache(db, services.DefaultTranslationCacheSize)
+	grammarDetector := services.NewGrammarDetector(llmRouter, translationCache)
 	deepgramService := services.NewDeepgramService()
-	chunkAnalyzer := services.NewChunkAnalyzer(grammarDetector)
+	fluencyAnalyzer := services.NewFluencyAnalyzer()
+	pronunciationAnalyzer := services.NewPronunciationAnalyzer()
+	lexicalAnalyzer := services.NewLexicalAnalyzer()
+	chunkAnalyzer := services.NewChunkAnalyzer(grammarDetector, fluencyAnalyzer, pronunciationAnalyzer, lexicalAnalyzer)
 	interviewerService := services.NewInterviewerService()
 	openaiRealtimeService := services.NewOpenAIRealtimeService()
 
+	feedbackService := services.NewFeedbackService(db)
+	userPreferences := services.NewUserPreferences(db)
+	sessionStore := services.NewSessionStore(db)
+	loadCtx, cancelLoad := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := feedbackService.Load(loadCtx); err != nil {
+		log.Printf("Error loading rule feedback: %v", err)
+	}
+	cancelLoad()
+	go feedbackService.Run(ruleFeedbackInterval)
+
 	// Initialize WebSocket hub
 	hub := websocket.NewHub()
 	go hub.Run()
 
-	wsHandler := websocket.NewHandler(hub, grammarDetector, deepgramService, chunkAnalyzer)
+	wsHandler := websocket.NewHandler(hub, grammarDetector, deepgramService, chunkAnalyzer, fluencyAnalyzer, pronunciationAnalyzer, feedbackService, userPreferences, interviewerService, sessionStore)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -53,6 +108,7 @@ func main() {
 			"active_clients":   hub.GetClientCount(),
 			"deepgram_configured": deepgramService != nil,
 			"openai_configured":   openaiRealtimeService.IsConfigured(),
+			"supabase_configured": db != nil,
 		})
 	})
 
@@ -79,8 +135,14 @@ func main() {
 	// Grammar check endpoint (for testing)
 	api.Post("/check-grammar", func(c *fiber.Ctx) error {
 		var request struct {
+			UserID         string `json:"user_id"` // Lets the user dispute the findings
 			Text           string `json:"text"`
 			NativeLanguage string `json:"native_language"`
+			Profile        string `json:"profile"`
+			Style          string `json:"explanation_style"`
+			Depth          string `json:"explanation_depth"`
+			Mode           string `json:"mode"` // Interviewer persona whose strictness sets the automatic depth
+			Merge          bool   `json:"merge"` // Ask the LLM even when rules matched
 		}
 
 		if err := c.BodyParser(&request); err != nil {
@@ -93,23 +155,134 @@ func main() {
 			request.NativeLanguage = "Hindi"
 		}
 
-		result, err := grammarDetector.DetectGrammarError(request.Text, request.NativeLanguage)
+		if rules.Active().Profile(request.Profile) == nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Unknown rule profile",
+			})
+		}
+
+		style, err := services.ParseExplanationStyle(request.Style)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		depth, err := services.ParseExplanationDepth(request.Depth)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		results, err := grammarDetector.DetectGrammarErrors(c.UserContext(), request.Text, services.DetectOptions{
+			NativeLanguage: request.NativeLanguage,
+			Profile:        request.Profile,
+			Style:          style,
+			Merge:          request.Merge,
+		})
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{
 				"error": err.Error(),
 			})
 		}
 
-		if result == nil {
+		if len(results) == 0 {
 			return c.JSON(fiber.Map{
 				"has_error": false,
 				"message":   "No grammar errors detected",
 			})
 		}
 
+		feedbackService.RecordShown(request.UserID, results)
+
+		strictness := interviewerService.GetPersona(request.Mode).StrictnessLevel
+		for _, result := range results {
+			result.SetDepth(services.ChooseDepth(depth, strictness, 0))
+		}
+
 		return c.JSON(fiber.Map{
 			"has_error": true,
-			"result":    result,
+			"result":    results[0],
+			"errors":    results,
+		})
+	})
+
+	// Report a finding that was not a mistake
+	api.Post("/disputes", limiter.New(limiter.Config{
+		Max:        disputesPerMinute,
+		Expiration: time.Minute,
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(429).JSON(fiber.Map{
+				"error": "Too many disputes, try again later",
+			})
+		},
+	}), func(c *fiber.Ctx) error {
+		var dispute services.Dispute
+
+		if err := c.BodyParser(&dispute); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		dispute.CreatedAt = time.Time{}
+		if err := feedbackService.Dispute(dispute); err != nil {
+			status := 400
+			switch {
+			case errors.Is(err, services.ErrUnknownRule):
+				status = 404
+			case errors.Is(err, services.ErrNotShown):
+				status = 403
+			}
+			return c.Status(status).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.Status(201).JSON(fiber.Map{
+			"message": "Dispute recorded",
+			"rule_id": dispute.RuleID,
+		})
+	})
+
+	// Rule catalogue endpoints
+	api.Get("/rules", func(c *fiber.Ctx) error {
+		filter := rules.CatalogueFilter{
+			ErrorType: c.Query("error_type"),
+			Category:  c.Query("category"),
+			Level:     rules.Level(strings.ToUpper(c.Query("level"))),
+			Tag:       c.Query("tag"),
+			Pack:      c.Query("pack"),
+		}
+		if filter.Level != "" && !filter.Level.Valid() {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "level must be one of A1, A2, B1, B2, C1, C2",
+			})
+		}
+
+		entries := rules.Active().Catalogue(filter)
+		return c.JSON(fiber.Map{
+			"rules": entries,
+			"count": len(entries),
+		})
+	})
+
+	api.Get("/rules/:id", func(c *fiber.Ctx) error {
+		rule := rules.Active().Lookup(strings.ToUpper(c.Params("id")))
+		if rule == nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Rule not found",
+			})
+		}
+		return c.JSON(rule.Info())
+	})
+
+	// Rule profiles endpoint
+	api.Get("/rule-profiles", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"default":  rules.DefaultProfile,
+			"profiles": rules.Active().ProfileList(),
 		})
 	})
 
@@ -165,14 +338,220 @@ func main() {
 		return c.JSON(stats)
 	})
 
+	// Explanation style and depth preferences of a user
+	api.Get("/users/:id/preferences", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"user_id":            c.Params("id"),
+			"explanation_style":  userPreferences.ExplanationStyle(c.UserContext(), c.Params("id")),
+			"explanation_styles": services.ExplanationStyles,
+			"explanation_depth":  userPreferences.ExplanationDepth(c.UserContext(), c.Params("id")),
+			"explanation_depths": append([]string{services.DepthAuto}, services.ExplanationDepths...),
+		})
+	})
+
+	api.Put("/users/:id/preferences", func(c *fiber.Ctx) error {
+		var request struct {
+			ExplanationStyle string `json:"explanation_style"`
+			ExplanationDepth string `json:"explanation_depth"`
+		}
+
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		if request.ExplanationStyle == "" && request.ExplanationDepth == "" {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "explanation_style or explanation_depth is required",
+			})
+		}
+
+		style, err := services.ParseExplanationStyle(request.ExplanationStyle)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error":              "explanation_style must be one of the explanation styles",
+				"explanation_styles": services.ExplanationStyles,
+			})
+		}
+
+		depth, err := services.ParseExplanationDepth(request.ExplanationDepth)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error":              "explanation_depth must be auto or one of the explanation depths",
+				"explanation_depths": services.ExplanationDepths,
+			})
+		}
+
+		ctx, userID := c.UserContext(), c.Params("id")
+		if request.ExplanationStyle != "" {
+			err = userPreferences.SetExplanationStyle(ctx, userID, style)
+		}
+		if err == nil && request.ExplanationDepth != "" {
+			err = userPreferences.SetExplanationDepth(ctx, userID, depth)
+		}
+		if err != nil {
+			log.Printf("Error saving preferences: %v", err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to save preferences",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"user_id":           userID,
+			"explanation_style": userPreferences.ExplanationStyle(ctx, userID),
+			"explanation_depth": userPreferences.ExplanationDepth(ctx, userID),
+		})
+	})
+
 	// Supported languages endpoint
 	api.Get("/languages", func(c *fiber.Ctx) error {
-		languages := []string{
-			"Hindi", "Tamil", "Telugu", "Marathi", 
-			"Punjabi", "Bengali", "Gujarati", "Kannada", "Malayalam",
+		return c.JSON(fiber.Map{
+			"languages": supportedLanguages,
+		})
+	})
+
+	// Native-language rule packs endpoint
+	api.Get("/rule-packs", func(c *fiber.Ctx) error {
+		ruleSet := rules.Active()
+
+		if language := c.Query("native_language"); language != "" {
+			return c.JSON(fiber.Map{
+				"native_language": language,
+				"packs":           ruleSet.PacksFor(language),
+			})
+		}
+
+		byLanguage := make(map[string][]string, len(supportedLanguages))
+		for _, language := range supportedLanguages {
+			names := make([]string, 0)
+			for _, pack := range ruleSet.PacksFor(language) {
+				names = append(names, pack.Name)
+			}
+			byLanguage[language] = names
 		}
+
 		return c.JSON(fiber.Map{
-			"languages": languages,
+			"packs":     ruleSet.PackList(),
+			"languages": byLanguage,
+		})
+	})
+
+	// Admin routes
+	admin := api.Group("/admin", requireAdmin(os.Getenv("ADMIN_API_KEY")))
+
+	admin.Get("/rule-feedback", func(c *fiber.Ctx) error {
+		limit := c.QueryInt("limit", 20)
+		report := feedbackService.Report(limit)
+
+		return c.JSON(fiber.Map{
+			"rules":     report,
+			"count":     len(report),
+			"overrides": rules.Overrides(),
+		})
+	})
+
+	admin.Post("/rule-feedback/adjust", func(c *fiber.Ctx) error {
+		changed := feedbackService.Adjust(c.UserContext())
+
+		return c.JSON(fiber.Map{
+			"changed":   changed,
+			"overrides": rules.Overrides(),
+		})
+	})
+
+	admin.Get("/translations", func(c *fiber.Ctx) error {
+		filter := services.TranslationFilter{
+			Language: c.Query("language"),
+			Limit:    c.QueryInt("limit", 50),
+		}
+		if approved := c.Query("approved"); approved != "" {
+			value := approved == "true"
+			filter.Approved = &value
+		}
+
+		translations, err := translationCache.List(c.UserContext(), filter)
+		if err != nil {
+			log.Printf("Error listing translations: %v", err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to list translations",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"translations": translations,
+			"count":        len(translations),
+		})
+	})
+
+	admin.Put("/translations", func(c *fiber.Ctx) error {
+		var req struct {
+			services.TranslationKey
+			services.TranslationEdit
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+		if req.English == "" || req.Language == "" {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "english and language are required",
+			})
+		}
+		if req.Translation != nil && strings.TrimSpace(*req.Translation) == "" {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "translation cannot be empty",
+			})
+		}
+
+		translation, err := translationCache.Update(c.UserContext(), req.TranslationKey, req.TranslationEdit)
+		if errors.Is(err, services.ErrTranslationNotFound) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err != nil {
+			log.Printf("Error updating translation: %v", err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to update translation",
+			})
+		}
+
+		return c.JSON(translation)
+	})
+
+	// Approved translations as a catalogue file, to merge into internal/messages/data
+	admin.Get("/translations/catalogue", func(c *fiber.Ctx) error {
+		language := c.Query("language")
+		if language == "" {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "language is required",
+			})
+		}
+
+		file, err := translationCache.CatalogueFile(c.UserContext(), language, messages.Default())
+		if err != nil {
+			log.Printf("Error exporting translations: %v", err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to export translations",
+			})
+		}
+
+		data, err := yaml.Marshal(file)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to export translations",
+			})
+		}
+
+		c.Set(fiber.HeaderContentType, "application/yaml; charset=utf-8")
+		return c.Send(data)
+	})
+
+	admin.Get("/llm-stats", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"providers": llmRouter.Stats(),
 		})
 	})
 
@@ -185,3 +564,21 @@ func main() {
 	log.Printf("🚀 Vartalaap AI 2.0 server starting on port %s", port)
 	log.Fatal(app.Listen(":" + port))
 }
+
+// requireAdmin only lets through requests carrying key as a bearer token.
+// With no key configured the admin routes are closed.
+func requireAdmin(key string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if key == "" {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Admin API disabled, set ADMIN_API_KEY",
+			})
+		}
+		if subtle.ConstantTimeCompare([]byte(c.Get("Authorization")), []byte("Bearer "+key)) != 1 {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "Invalid admin key",
+			})
+		}
+		return c.Next()
+	}
+}
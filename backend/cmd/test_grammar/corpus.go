@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Sample is one labeled sentence from a corpus file
+type Sample struct {
+	Sentence       string   `json:"sentence"`
+	Rules          []string `json:"rules"`           // Rule IDs expected to fire, empty for a correct sentence
+	Correction     string   `json:"correction"`      // Expected corrected sentence, optional
+	NativeLanguage string   `json:"native_language"` // Speaker's native language, selects rule packs
+
+	source string // file:line, for reporting
+}
+
+// loadCorpora reads every JSON Lines corpus file in paths
+func loadCorpora(paths []string) ([]Sample, error) {
+	samples := make([]Sample, 0)
+	for _, path := range paths {
+		fileSamples, err := loadCorpus(path)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, fileSamples...)
+	}
+	return samples, nil
+}
+
+// loadCorpus reads a JSON Lines file with one Sample per line.
+// Blank lines and lines starting with # are skipped.
+func loadCorpus(path string) ([]Sample, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	samples := make([]Sample, 0)
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var sample Sample
+		if err := json.Unmarshal([]byte(line), &sample); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+		}
+		if sample.Sentence == "" {
+			return nil, fmt.Errorf("%s:%d: sentence is required", path, lineNumber)
+		}
+		sample.source = fmt.Sprintf("%s:%d", path, lineNumber)
+		samples = append(samples, sample)
+	}
+
+	return samples, scanner.Err()
+}
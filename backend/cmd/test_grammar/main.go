@@ -1,46 +1,161 @@
+// Command test_grammar evaluates the grammar rules against labeled corpora.
+//
+// Each corpus is a JSON Lines file with one sentence per line:
+//
+//	{"sentence": "He have a car", "rules": ["HE_HAVE"], "correction": "He has a car"}
+//
+// Samples with a native_language also run the rule packs for that language.
+//
+// It reports precision, recall and F1 per rule and per error type, lists
+// false positives, and exits non-zero when a run regresses against the
+// baseline, so rule edits can be gated in CI.
 package main
 
 import (
-"fmt"
-"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/rules"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/rules"
 )
 
 func main() {
-testCases := []string{
-"I has a book",
-"Yesterday I go to the market",
-"Please do the needful",
-"I want to prepone the meeting",
-"Could of done better",
-"This things are good",
-"He have a car",
-"They is coming",
-"This is correct sentence",
-}
+	corpusFlag := flag.String("corpus", "cmd/test_grammar/testdata/corpus.jsonl", "comma-separated list of corpus files")
+	rulesDir := flag.String("rules", "", "directory of rule files to evaluate instead of the built-in rules")
+	baselinePath := flag.String("baseline", "", "compare against this baseline and exit 1 on regression")
+	writePath := flag.String("write-baseline", "", "save the results as a new baseline")
+	profile := flag.String("profile", "", "rule profile to evaluate, e.g. casual")
+	verbose := flag.Bool("v", false, "also list missed errors and wrong corrections")
+	flag.Parse()
+
+	if *rulesDir != "" {
+		ruleSet, err := rules.LoadDir(*rulesDir)
+		if err != nil {
+			log.Fatalf("Failed to load rules: %v", err)
+		}
+		rules.SetActive(ruleSet)
+	}
+
+	samples, err := loadCorpora(strings.Split(*corpusFlag, ","))
+	if err != nil {
+		log.Fatalf("Failed to load corpus: %v", err)
+	}
+
+	if rules.Active().Profile(*profile) == nil {
+		log.Fatalf("Unknown rule profile %q", *profile)
+	}
 
-fmt.Println("Testing Grammar Rules:")
-fmt.Println("======================")
-
-correctCount := 0
-errorCount := 0
-
-for _, text := range testCases {
-rule, corrected := rules.DetectError(text)
-if rule != nil {
-errorCount++
-fmt.Printf("\n✗ Error Detected: %s\n", text)
-fmt.Printf("  Type: %s\n", rule.ErrorType)
-fmt.Printf("  Corrected: %s\n", corrected)
-fmt.Printf("  Explanation: %s\n", rule.Description)
-} else {
-correctCount++
-fmt.Printf("\n✓ No Error: %s\n", text)
+	report := evaluate(samples, rules.Options{Profile: *profile})
+	printReport(os.Stdout, report, *verbose)
+
+	if *writePath != "" {
+		if err := writeBaseline(*writePath, report); err != nil {
+			log.Fatalf("Failed to write baseline: %v", err)
+		}
+		fmt.Printf("\nBaseline written to %s\n", *writePath)
+	}
+
+	if *baselinePath != "" {
+		baseline, err := loadBaseline(*baselinePath)
+		if err != nil {
+			log.Fatalf("Failed to load baseline: %v", err)
+		}
+
+		regressions := compareBaseline(os.Stdout, baseline, report)
+		if len(regressions) > 0 {
+			fmt.Printf("\n%d regression(s) against %s:\n", len(regressions), *baselinePath)
+			for _, regression := range regressions {
+				fmt.Printf("  - %s\n", regression)
+			}
+			os.Exit(1)
+		}
+		fmt.Println("\nNo regressions against baseline")
+	}
 }
+
+// evaluate runs the active rule set over every sample and scores the results
+func evaluate(samples []Sample, opts rules.Options) *Report {
+	ruleSet := rules.Active()
+	report := &Report{
+		Samples:        len(samples),
+		ByRule:         make(map[string]Counts),
+		ByErrorType:    make(map[string]Counts),
+		FalsePositives: make([]FalsePositive, 0),
+	}
+
+	for _, sample := range samples {
+		sampleOpts := opts
+		sampleOpts.NativeLanguage = sample.NativeLanguage
+		matches := ruleSet.DetectAllWith(sample.Sentence, sampleOpts)
+
+		expected := make(map[string]bool, len(sample.Rules))
+		for _, id := range sample.Rules {
+			expected[id] = true
+		}
+
+		detected := make(map[string]string, len(matches))
+		for _, m := range matches {
+			if _, seen := detected[m.Rule.ID]; !seen {
+				detected[m.Rule.ID] = m.Text
+			}
+		}
+
+		for id, matched := range detected {
+			var c Counts
+			if expected[id] {
+				c.TruePositives = 1
+			} else {
+				c.FalsePositives = 1
+				report.FalsePositives = append(report.FalsePositives, FalsePositive{
+					Sentence: sample.Sentence,
+					RuleID:   id,
+					Matched:  matched,
+				})
+			}
+			report.record(ruleSet, id, c)
+		}
+
+		for id := range expected {
+			if _, found := detected[id]; !found {
+				report.record(ruleSet, id, Counts{FalseNegatives: 1})
+				report.Misses = append(report.Misses, Miss{Sentence: sample.Sentence, RuleID: id})
+			}
+		}
+
+		if sample.Correction != "" {
+			report.CorrectionsChecked++
+			corrected := rules.ApplyMatches(sample.Sentence, matches)
+			if corrected == sample.Correction {
+				report.CorrectionsRight++
+			} else {
+				report.WrongCorrections = append(report.WrongCorrections, WrongCorrection{
+					Sentence: sample.Sentence,
+					Expected: sample.Correction,
+					Got:      corrected,
+				})
+			}
+		}
+	}
+
+	return report
 }
 
-fmt.Printf("\n\nSummary:\n")
-fmt.Printf("========\n")
-fmt.Printf("Total test cases: %d\n", len(testCases))
-fmt.Printf("Errors detected: %d\n", errorCount)
-fmt.Printf("Correct sentences: %d\n", correctCount)
+// record adds counts for a rule to the rule, error type and overall totals
+func (r *Report) record(ruleSet *rules.RuleSet, ruleID string, c Counts) {
+	errorType := "Unknown Rule"
+	if rule := ruleSet.Lookup(ruleID); rule != nil {
+		errorType = rule.ErrorType
+	}
+
+	byRule := r.ByRule[ruleID]
+	byRule.add(c)
+	r.ByRule[ruleID] = byRule
+
+	byType := r.ByErrorType[errorType]
+	byType.add(c)
+	r.ByErrorType[errorType] = byType
+
+	r.Overall.add(c)
 }
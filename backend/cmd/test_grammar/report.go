@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// Counts holds detection outcomes for a rule or error type
+type Counts struct {
+	TruePositives  int `json:"tp"`
+	FalsePositives int `json:"fp"`
+	FalseNegatives int `json:"fn"`
+}
+
+// Precision is the share of detections that were expected
+func (c Counts) Precision() float64 {
+	return ratio(c.TruePositives, c.TruePositives+c.FalsePositives)
+}
+
+// Recall is the share of expected errors that were detected
+func (c Counts) Recall() float64 {
+	return ratio(c.TruePositives, c.TruePositives+c.FalseNegatives)
+}
+
+// F1 is the harmonic mean of precision and recall
+func (c Counts) F1() float64 {
+	p, r := c.Precision(), c.Recall()
+	if p+r == 0 {
+		return 0
+	}
+	return 2 * p * r / (p + r)
+}
+
+func (c *Counts) add(other Counts) {
+	c.TruePositives += other.TruePositives
+	c.FalsePositives += other.FalsePositives
+	c.FalseNegatives += other.FalseNegatives
+}
+
+// ratio returns n/d, treating an empty denominator as a perfect score
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 1
+	}
+	return float64(n) / float64(d)
+}
+
+// Report is the outcome of evaluating the rule engine against a corpus.
+// It doubles as the baseline file format.
+type Report struct {
+	Samples            int               `json:"samples"`
+	Overall            Counts            `json:"overall"`
+	ByRule             map[string]Counts `json:"by_rule"`
+	ByErrorType        map[string]Counts `json:"by_error_type"`
+	CorrectionsChecked int               `json:"corrections_checked"`
+	CorrectionsRight   int               `json:"corrections_right"`
+	FalsePositives     []FalsePositive   `json:"false_positives"`
+	Misses             []Miss            `json:"misses,omitempty"`
+	WrongCorrections   []WrongCorrection `json:"wrong_corrections,omitempty"`
+}
+
+// FalsePositive is a rule that fired on a sentence where it was not expected
+type FalsePositive struct {
+	Sentence string `json:"sentence"`
+	RuleID   string `json:"rule_id"`
+	Matched  string `json:"matched"`
+}
+
+// Miss is an expected rule that did not fire
+type Miss struct {
+	Sentence string `json:"sentence"`
+	RuleID   string `json:"rule_id"`
+}
+
+// WrongCorrection is a corrected sentence that differs from the label
+type WrongCorrection struct {
+	Sentence string `json:"sentence"`
+	Expected string `json:"expected"`
+	Got      string `json:"got"`
+}
+
+// CorrectionAccuracy is the share of labeled corrections reproduced exactly
+func (r *Report) CorrectionAccuracy() float64 {
+	return ratio(r.CorrectionsRight, r.CorrectionsChecked)
+}
+
+// loadBaseline reads a report saved with -write-baseline
+func loadBaseline(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var baseline Report
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &baseline, nil
+}
+
+// writeBaseline saves the report so later runs can be compared against it
+func writeBaseline(path string, report *Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// printReport writes the scores per rule and per error type
+func printReport(w io.Writer, report *Report, verbose bool) {
+	fmt.Fprintf(w, "Evaluated %d sentences\n\n", report.Samples)
+
+	fmt.Fprintln(w, "By error type:")
+	printCounts(w, report.ByErrorType)
+
+	fmt.Fprintln(w, "\nBy rule:")
+	printCounts(w, report.ByRule)
+
+	fmt.Fprintf(w, "\nOverall: P=%.3f R=%.3f F1=%.3f  corrections %d/%d (%.1f%%)\n",
+		report.Overall.Precision(), report.Overall.Recall(), report.Overall.F1(),
+		report.CorrectionsRight, report.CorrectionsChecked, report.CorrectionAccuracy()*100)
+
+	if len(report.FalsePositives) > 0 {
+		fmt.Fprintf(w, "\nFalse positives (%d):\n", len(report.FalsePositives))
+		for _, fp := range report.FalsePositives {
+			fmt.Fprintf(w, "  ✗ %-20s %q (matched %q)\n", fp.RuleID, fp.Sentence, fp.Matched)
+		}
+	}
+
+	if !verbose {
+		return
+	}
+
+	if len(report.Misses) > 0 {
+		fmt.Fprintf(w, "\nMissed errors (%d):\n", len(report.Misses))
+		for _, miss := range report.Misses {
+			fmt.Fprintf(w, "  ? %-20s %q\n", miss.RuleID, miss.Sentence)
+		}
+	}
+
+	if len(report.WrongCorrections) > 0 {
+		fmt.Fprintf(w, "\nWrong corrections (%d):\n", len(report.WrongCorrections))
+		for _, wrong := range report.WrongCorrections {
+			fmt.Fprintf(w, "  %q\n    expected %q\n    got      %q\n", wrong.Sentence, wrong.Expected, wrong.Got)
+		}
+	}
+}
+
+func printCounts(w io.Writer, counts map[string]Counts) {
+	fmt.Fprintf(w, "  %-28s %4s %4s %4s %7s %7s %7s\n", "", "TP", "FP", "FN", "P", "R", "F1")
+	for _, key := range sortedKeys(counts) {
+		c := counts[key]
+		fmt.Fprintf(w, "  %-28s %4d %4d %4d %7.3f %7.3f %7.3f\n",
+			key, c.TruePositives, c.FalsePositives, c.FalseNegatives, c.Precision(), c.Recall(), c.F1())
+	}
+}
+
+// compareBaseline prints the rules whose scores changed and returns the
+// regressions: lower F1 for any rule or overall, or lower correction accuracy
+func compareBaseline(w io.Writer, baseline, current *Report) []string {
+	regressions := make([]string, 0)
+
+	fmt.Fprintln(w, "\nChanges against baseline:")
+	changed := false
+	keys := make(map[string]bool)
+	for key := range baseline.ByRule {
+		keys[key] = true
+	}
+	for key := range current.ByRule {
+		keys[key] = true
+	}
+	for _, key := range sortedKeys(keys) {
+		before, after := baseline.ByRule[key], current.ByRule[key]
+		if before == after {
+			continue
+		}
+		changed = true
+		fmt.Fprintf(w, "  %-28s F1 %.3f -> %.3f  (tp %d->%d, fp %d->%d, fn %d->%d)\n",
+			key, before.F1(), after.F1(),
+			before.TruePositives, after.TruePositives,
+			before.FalsePositives, after.FalsePositives,
+			before.FalseNegatives, after.FalseNegatives)
+		if after.F1() < before.F1() {
+			regressions = append(regressions, fmt.Sprintf("rule %s: F1 %.3f -> %.3f", key, before.F1(), after.F1()))
+		}
+	}
+	if !changed {
+		fmt.Fprintln(w, "  (no rule changed)")
+	}
+
+	if current.Overall.F1() < baseline.Overall.F1() {
+		regressions = append(regressions, fmt.Sprintf("overall F1 %.3f -> %.3f", baseline.Overall.F1(), current.Overall.F1()))
+	}
+	if current.CorrectionAccuracy() < baseline.CorrectionAccuracy() {
+		regressions = append(regressions, fmt.Sprintf("correction accuracy %.3f -> %.3f", baseline.CorrectionAccuracy(), current.CorrectionAccuracy()))
+	}
+
+	return regressions
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,330 @@
+// Package messages holds the native-language explanations of the grammar
+// rules: one file per language, keyed by rule ID.
+package messages
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/strictyaml"
+)
+
+//go:embed data/*.yaml
+var defaultFiles embed.FS
+
+// File is the on-disk format of a language file
+type File struct {
+	Version  int               `yaml:"version"`
+	Language string            `yaml:"language"`
+	Fallback []string          `yaml:"fallback,omitempty"` // Languages to try, in order, for rules this file lacks
+	Messages map[string]string `yaml:"messages"`           // Explanations by rule ID
+
+	// Explanations in other styles, by style and rule ID, e.g. Hinglish
+	// under code_mixed
+	Styles map[string]map[string]string `yaml:"styles,omitempty"`
+}
+
+// NativeStyle is the style of Messages: the language in its own script
+const NativeStyle = "native"
+
+// Args fills the placeholders of a message
+type Args struct {
+	Original          string // {original}: the words in error
+	Corrected         string // {corrected}: the words that replace them
+	Sentence          string // {sentence}: the whole utterance
+	CorrectedSentence string // {corrected_sentence}: the utterance with every error fixed
+}
+
+// Placeholders lists the placeholders a message may use
+var Placeholders = []string{"original", "corrected", "sentence", "corrected_sentence"}
+
+var placeholderPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// values returns the placeholder values by name
+func (a Args) values() map[string]string {
+	return map[string]string{
+		"original":           a.Original,
+		"corrected":          a.Corrected,
+		"sentence":           a.Sentence,
+		"corrected_sentence": a.CorrectedSentence,
+	}
+}
+
+// Language is the compiled catalogue of one language
+type Language struct {
+	Name     string
+	Fallback []string
+	Messages map[string]string
+	Styles   map[string]map[string]string
+}
+
+// Message is an explanation rendered for one finding
+type Message struct {
+	Text     string
+	Language string // The language the text is in, which may be a fallback
+}
+
+// Catalogue is the set of language files. It is immutable once loaded.
+type Catalogue struct {
+	languages map[string]*Language // By normalised name
+	Source    string
+}
+
+var defaultCatalogue *Catalogue
+
+func init() {
+	catalogue, err := LoadDefault()
+	if err != nil {
+		panic(fmt.Sprintf("messages: invalid built-in catalogue: %v", err))
+	}
+	defaultCatalogue = catalogue
+}
+
+// Default returns the catalogue built into the binary
+func Default() *Catalogue {
+	return defaultCatalogue
+}
+
+// LoadDefault compiles the language files built into the binary
+func LoadDefault() (*Catalogue, error) {
+	return loadFS(defaultFiles, "data", "built-in")
+}
+
+// LoadDir compiles every language file in dir
+func LoadDir(dir string) (*Catalogue, error) {
+	return loadFS(os.DirFS(dir), ".", dir)
+}
+
+func loadFS(fsys fs.FS, dir string, source string) (*Catalogue, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalogue{
+		languages: make(map[string]*Language),
+		Source:    source,
+	}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		language, err := parseFile(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+
+		key := languageKey(language.Name)
+		if _, exists := c.languages[key]; exists {
+			return nil, fmt.Errorf("%s: duplicate language %s", entry.Name(), language.Name)
+		}
+		c.languages[key] = language
+	}
+
+	if len(c.languages) == 0 {
+		return nil, fmt.Errorf("no language files found in %s", source)
+	}
+
+	for _, language := range c.languages {
+		for _, fallback := range language.Fallback {
+			if c.languages[languageKey(fallback)] == nil {
+				return nil, fmt.Errorf("%s: unknown fallback language %s", language.Name, fallback)
+			}
+		}
+	}
+	return c, nil
+}
+
+// parseFile decodes and validates a language file
+func parseFile(data []byte) (*Language, error) {
+	var file File
+	if err := strictyaml.Decode(data, &file); err != nil {
+		return nil, err
+	}
+
+	if file.Version != 1 {
+		return nil, fmt.Errorf("unsupported message file version %d", file.Version)
+	}
+	if file.Language == "" {
+		return nil, fmt.Errorf("language is required")
+	}
+
+	if err := checkMessages(file.Messages); err != nil {
+		return nil, err
+	}
+	for style, messages := range file.Styles {
+		if style == "" || style == NativeStyle {
+			return nil, fmt.Errorf("style %q: the native style goes under messages", style)
+		}
+		if err := checkMessages(messages); err != nil {
+			return nil, fmt.Errorf("style %s: %w", style, err)
+		}
+	}
+
+	return &Language{
+		Name:     file.Language,
+		Fallback: file.Fallback,
+		Messages: file.Messages,
+		Styles:   file.Styles,
+	}, nil
+}
+
+// checkMessages rejects empty messages and unknown placeholders
+func checkMessages(messages map[string]string) error {
+	for id, text := range messages {
+		if strings.TrimSpace(text) == "" {
+			return fmt.Errorf("message %s is empty", id)
+		}
+		for _, name := range placeholdersIn(text) {
+			if !isPlaceholder(name) {
+				return fmt.Errorf("message %s: unknown placeholder {%s}", id, name)
+			}
+		}
+	}
+	return nil
+}
+
+// Languages returns the names of the languages in the catalogue, sorted
+func (c *Catalogue) Languages() []string {
+	names := make([]string, 0, len(c.languages))
+	for _, language := range c.languages {
+		names = append(names, language.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Language returns the catalogue of a language, or nil if there is none
+func (c *Catalogue) Language(name string) *Language {
+	return c.languages[languageKey(name)]
+}
+
+// Chain returns the languages tried for name: name itself, then its
+// fallbacks and theirs, each once. Unknown languages have an empty chain.
+func (c *Catalogue) Chain(name string) []*Language {
+	chain := make([]*Language, 0, 2)
+	seen := make(map[string]bool)
+
+	var visit func(name string)
+	visit = func(name string) {
+		language := c.languages[languageKey(name)]
+		if language == nil || seen[languageKey(name)] {
+			return
+		}
+		seen[languageKey(name)] = true
+		chain = append(chain, language)
+		for _, fallback := range language.Fallback {
+			visit(fallback)
+		}
+	}
+	visit(name)
+
+	return chain
+}
+
+// Explain renders the explanation of a rule in language, or the first
+// fallback language that has one. A message needing a placeholder that
+// args leaves empty is skipped. It returns false if no language in the
+// chain explains the rule; the caller then uses the English description.
+func (c *Catalogue) Explain(language, ruleID string, args Args) (Message, bool) {
+	return c.ExplainStyle(language, NativeStyle, ruleID, args)
+}
+
+// ExplainStyle is Explain for an explanation style. Only messages written
+// in the style are used, along the same fallback chain.
+func (c *Catalogue) ExplainStyle(language, style, ruleID string, args Args) (Message, bool) {
+	values := args.values()
+
+	for _, candidate := range c.Chain(language) {
+		text, ok := candidate.messages(style)[ruleID]
+		if !ok || !hasValues(text, values) {
+			continue
+		}
+		return Message{Text: render(text, values), Language: candidate.Name}, true
+	}
+	return Message{}, false
+}
+
+// messages returns the messages of a style, nil if there are none
+func (l *Language) messages(style string) map[string]string {
+	if style == "" || style == NativeStyle {
+		return l.Messages
+	}
+	return l.Styles[style]
+}
+
+// Missing returns, for every language, the rule IDs it has no message for
+// itself, ignoring fallbacks
+func (c *Catalogue) Missing(ruleIDs []string) map[string][]string {
+	missing := make(map[string][]string, len(c.languages))
+	for _, language := range c.languages {
+		ids := make([]string, 0)
+		for _, id := range ruleIDs {
+			if _, ok := language.Messages[id]; !ok {
+				ids = append(ids, id)
+			}
+		}
+		missing[language.Name] = ids
+	}
+	return missing
+}
+
+// PlaceholdersIn returns the placeholders a message uses
+func PlaceholdersIn(text string) []string {
+	return placeholdersIn(text)
+}
+
+// render fills the placeholders of text
+func render(text string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		return values[placeholder[1:len(placeholder)-1]]
+	})
+}
+
+// hasValues reports whether every placeholder text uses has a value
+func hasValues(text string, values map[string]string) bool {
+	for _, name := range placeholdersIn(text) {
+		if values[name] == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// placeholdersIn returns the names of the placeholders in text
+func placeholdersIn(text string) []string {
+	names := make([]string, 0)
+	for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// isPlaceholder reports whether name is one of Placeholders
+func isPlaceholder(name string) bool {
+	for _, placeholder := range Placeholders {
+		if name == placeholder {
+			return true
+		}
+	}
+	return false
+}
+
+// languageKey normalises a language name for lookups, e.g. " hindi" and "Hindi"
+func languageKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
@@ -0,0 +1,402 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// subject is the subject found in front of a verb
+type subject struct {
+	person Person
+	start  int // Index of the subject's first token
+	head   int // Index of the subject's last token
+}
+
+// personalPronouns are always subjects, wherever they stand in the clause
+var personalPronouns = map[string]Person{
+	"i": FirstSingular, "he": ThirdSingular, "she": ThirdSingular,
+	"we": Plural, "they": Plural,
+}
+
+// singularDeterminers and pluralDeterminers fix the number of the noun
+// phrase they start; other determiners such as "the" and "my" leave it to
+// the noun
+var (
+	singularDeterminers = wordSet(`a an this that every each another one`)
+	pluralDeterminers   = wordSet(`these those two three four five six seven eight nine ten many several few both`)
+	determiners         = wordSet(`the my your his her its our their a an this that these those every each another one
+		two three four five six seven eight nine ten many several few both`)
+)
+
+// openers can precede a subject: conjunctions, question words and verbs
+// taking a clause, as in "I think my brother like cricket"
+var openers = wordSet(`and but or so because although though while whereas unless if when whenever
+	that what where why how whether
+	yesterday today tomorrow tonight ago week month year night weekend morning evening
+	think thought believe believed hope know knew feel felt guess said say says suppose mean heard sure`)
+
+// inverters before a subject mean the verb after it is not finite: "does
+// she go", "to let them go"
+var inverters = wordSet(`do does did don't doesn't didn't will would can could shall should may might must
+	won't wouldn't can't couldn't shouldn't is are was were am has have had to let make help`)
+
+// nonHeads never head a subject noun phrase, because they are not nouns or
+// their number is unclear: "there are", "all are", "the team is/are"
+var nonHeads = wordSet(`
+	there here now then okay ok well yes yeah no please sir madam hi hello
+	all some most none both many few several more half lot plenty enough
+	data news series species means physics mathematics economics politics
+	team family staff crew audience committee government management group department class company
+`)
+
+// subjunctives take the base form in a that-clause: "I suggest that he go"
+var subjunctives = wordSet(`suggest suggested recommend recommended insist insisted demand demanded
+	request requested propose proposed essential important vital necessary`)
+
+// negativeForms maps negative contractions to the verb form they contain
+var negativeForms = map[string]string{
+	"don't": "do", "doesn't": "does", "haven't": "have", "hasn't": "has",
+	"isn't": "is", "aren't": "are", "wasn't": "was", "weren't": "were",
+}
+
+// findSubject returns the subject of the verb at tokens[i]. ok is false
+// when there is none or it cannot be told apart from an object, e.g. in
+// "I saw my brother play".
+func findSubject(tokens []token, i int) (subject, bool) {
+	j := i - 1
+	for j >= 0 && adverbs[tokens[j].Lower] {
+		j--
+	}
+	if j < 0 {
+		return subject{}, false
+	}
+
+	var s subject
+	if person, ok := personalPronouns[tokens[j].Lower]; ok {
+		s = subject{person: person, start: j, head: j}
+	} else {
+		phrase, ok := nounPhrase(tokens, j)
+		if !ok || !opensClause(tokens, phrase.start) {
+			return subject{}, false
+		}
+		s = phrase
+	}
+
+	// "my brother and I", "Priya and Rahul"
+	if s.start >= 2 && tokens[s.start-1].Lower == "and" {
+		leftStart := s.start - 2
+		if _, pronoun := personalPronouns[tokens[leftStart].Lower]; !pronoun {
+			phrase, isPhrase := nounPhrase(tokens, s.start-2)
+			if !isPhrase {
+				return subject{}, false
+			}
+			leftStart = phrase.start
+		}
+		if !opensClause(tokens, leftStart) {
+			return subject{}, false
+		}
+		s = subject{person: Plural, start: leftStart, head: s.head}
+	} else if s.start >= 2 && tokens[s.start-1].Lower == "or" {
+		return subject{}, false
+	}
+
+	if s.start > 0 && inverters[tokens[s.start-1].Lower] || isSubjunctive(tokens, s.start) {
+		return subject{}, false
+	}
+	return s, true
+}
+
+// nounPhrase reads a simple noun phrase ending at tokens[head]: a
+// determiner, at most two modifiers and the noun, as in "my elder brother"
+func nounPhrase(tokens []token, head int) (subject, bool) {
+	word := tokens[head].Lower
+	if notSubject[word] || nonHeads[word] || strings.HasSuffix(word, "'s") {
+		return subject{}, false
+	}
+	// Only an -ing noun after a determiner ("the meeting") can head a
+	// phrase among the verb forms
+	_, forms, isVerb := LookupVerb(word)
+	if isVerb && (forms != FormIng || head == 0 || !determiners[tokens[head-1].Lower]) {
+		return subject{}, false
+	}
+
+	start, determiner := head, ""
+	for k := head - 1; k >= 0 && head-k <= 3; k-- {
+		previous := tokens[k].Lower
+		if determiners[previous] {
+			start, determiner = k, previous
+			break
+		}
+		if !isModifier(previous) || head-k > 2 {
+			break
+		}
+		start = k
+	}
+
+	person := ThirdSingular
+	if pronoun, ok := subjectPronouns[word]; ok {
+		person = pronoun
+	} else if isPluralNoun(word) {
+		person = Plural
+	}
+
+	// "this things" and "these thing" are left to THIS_THINGS and THESE_THING
+	if (singularDeterminers[determiner] && person == Plural) || (pluralDeterminers[determiner] && person != Plural) {
+		return subject{}, false
+	}
+	return subject{person: person, start: start, head: head}, true
+}
+
+// isModifier reports whether a word can sit between a determiner and its
+// noun, such as "elder" or "final-year"
+func isModifier(word string) bool {
+	if notSubject[word] || nonHeads[word] || adverbs[word] {
+		return false
+	}
+	if _, isPronoun := subjectPronouns[word]; isPronoun {
+		return false
+	}
+	_, forms, isVerb := LookupVerb(word)
+	return !isVerb || forms&(FormBase|FormThird) == 0
+}
+
+// opensClause reports whether a subject may start at tokens[start]
+func opensClause(tokens []token, start int) bool {
+	if start == 0 {
+		return true
+	}
+	previous := tokens[start-1].Lower
+	if openers[previous] {
+		return true
+	}
+	verb, _, ok := LookupVerb(previous)
+	return ok && openers[verb.Base]
+}
+
+// isSubjunctive reports whether the subject at tokens[start] opens a
+// that-clause after a word like "suggest"
+func isSubjunctive(tokens []token, start int) bool {
+	if start > 0 && subjunctives[tokens[start-1].Lower] {
+		return true
+	}
+	return start > 1 && tokens[start-1].Lower == "that" && subjunctives[tokens[start-2].Lower]
+}
+
+// agreementCandidate is a verb whose form depends on its subject
+type agreementCandidate struct {
+	index    int
+	verb     *Verb
+	form     Form
+	negative bool
+}
+
+// agreementCandidates returns the verbs in a clause whose form depends on
+// the subject, keeping only forms of be when forBe is set and the others when
+// it is not
+func agreementCandidates(tokens []token, forBe bool) []agreementCandidate {
+	candidates := make([]agreementCandidate, 0)
+	for i, t := range tokens {
+		word, negative := t.Lower, false
+		if plain, ok := negativeForms[word]; ok {
+			word, negative = plain, true
+		}
+		verb, forms, ok := LookupVerb(word)
+		if !ok || (verb == be) != forBe {
+			continue
+		}
+		if word == "be" || word == "been" || word == "being" {
+			continue
+		}
+		// "put" and "cut" may be past, which agrees with any subject
+		if forms.Has(FormPast) && verb.Base != "be" {
+			continue
+		}
+		if forms&(FormBase|FormThird|FormPast) == 0 {
+			continue
+		}
+		// A noun doubling as a verb before the real verb: "the bus stop is"
+		if !negative && i+1 < len(tokens) && (isAuxiliary(tokens[i+1].Lower) || modals[tokens[i+1].Lower]) && !isAuxiliary(word) {
+			continue
+		}
+		candidates = append(candidates, agreementCandidate{index: i, verb: verb, form: forms, negative: negative})
+	}
+	return candidates
+}
+
+// agreementClauses returns the clauses to check with, for each, whether a
+// past or duration time marker governs it. Those clauses are left to the
+// tense checkers unless the verb is already past.
+func agreementClauses(doc *document) (clauses [][]token, marked []bool) {
+	markedStarts := make(map[int]bool)
+	for _, clause := range markedClauses(doc, pastMarker, futureMarker) {
+		if len(clause.tokens) > 0 {
+			markedStarts[clause.tokens[0].Start] = true
+		}
+	}
+	for _, clause := range markedClauses(doc, durationMarker, pastMarker, futureMarker) {
+		if len(clause.tokens) > 0 {
+			markedStarts[clause.tokens[0].Start] = true
+		}
+	}
+
+	sentences := doc.segments(ScopeSentence)
+	var pending []token
+	for _, segment := range doc.segments(ScopeClause) {
+		tokens := doc.tokensIn(segment)
+		if len(tokens) == 0 {
+			continue
+		}
+		start := tokens[0].Start
+
+		// The segmenter splits "I and my friend go" before "and my", so a
+		// verbless clause is joined to a following one starting with "and"
+		if len(pending) > 0 && (tokens[0].Lower == "and" || tokens[0].Lower == "or") &&
+			segmentIndex(sentences, pending[0].Start) == segmentIndex(sentences, start) {
+			tokens = append(pending[:len(pending):len(pending)], tokens...)
+		}
+		pending = nil
+		if !hasVerb(tokens) && !hasNegativeForm(tokens) {
+			pending = tokens
+			continue
+		}
+		clauses = append(clauses, tokens)
+		marked = append(marked, markedStarts[start])
+	}
+	return clauses, marked
+}
+
+// hasNegativeForm reports whether any token is a negative contraction
+// such as "doesn't"
+func hasNegativeForm(tokens []token) bool {
+	for _, t := range tokens {
+		if _, ok := negativeForms[t.Lower]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkVerbAgreement flags present-tense verbs that do not agree with
+// their subject: "she go" becomes "she goes", "they has" becomes "they
+// have", "he don't" becomes "he doesn't"
+func checkVerbAgreement(r *GrammarRule, doc *document) []Match {
+	matches := make([]Match, 0)
+	clauses, marked := agreementClauses(doc)
+	for c, tokens := range clauses {
+		if marked[c] {
+			continue
+		}
+		for _, candidate := range agreementCandidates(tokens, false) {
+			t := tokens[candidate.index]
+			s, ok := findSubject(tokens, candidate.index)
+			if !ok || !likeIsVerb(tokens, candidate.index, s) {
+				continue
+			}
+
+			var correct string
+			switch {
+			case candidate.form.Has(FormBase) && s.person == ThirdSingular:
+				correct = candidate.verb.Third
+			case candidate.form.Has(FormThird) && s.person != ThirdSingular:
+				correct = candidate.verb.Base
+			default:
+				continue
+			}
+			if candidate.negative {
+				correct += "n't"
+			}
+			matches = append(matches, r.wordMatch(doc, t, t, correct,
+				fmt.Sprintf("Use '%s' with '%s'", correct, subjectText(doc, tokens, s))))
+		}
+	}
+	return matches
+}
+
+// checkBeAgreement flags forms of "be" that do not agree with their
+// subject: "the students was" becomes "the students were", "I is" becomes
+// "I am"
+func checkBeAgreement(r *GrammarRule, doc *document) []Match {
+	matches := make([]Match, 0)
+	clauses, marked := agreementClauses(doc)
+	for c, tokens := range clauses {
+		for _, candidate := range agreementCandidates(tokens, true) {
+			t := tokens[candidate.index]
+			past := candidate.form.Has(FormPast)
+			if marked[c] && !past {
+				continue
+			}
+			s, ok := findSubject(tokens, candidate.index)
+			if !ok {
+				continue
+			}
+
+			word := t.Lower
+			if plain, isNegative := negativeForms[word]; isNegative {
+				word = plain
+			}
+
+			correct := be.PresentFor(s.person)
+			if past {
+				// "if I were you", "I wish she were here"
+				if word == "were" && subjunctiveWere(tokens, s.start) {
+					continue
+				}
+				correct = be.PastFor(s.person == Plural)
+			}
+			if correct == word {
+				continue
+			}
+			if candidate.negative {
+				// There is no contraction of "am not"
+				if correct == "am" {
+					continue
+				}
+				correct += "n't"
+			}
+			matches = append(matches, r.wordMatch(doc, t, t, correct,
+				fmt.Sprintf("Use '%s' with '%s'", correct, subjectText(doc, tokens, s))))
+		}
+	}
+	return matches
+}
+
+// likeIsVerb tells the verb "like" from the preposition after a noun
+// phrase: "my brother like cricket" but "someone like you", "a person like
+// him is rare"
+func likeIsVerb(tokens []token, i int, s subject) bool {
+	if tokens[i].Lower != "like" {
+		return true
+	}
+	if _, pronoun := personalPronouns[tokens[s.head].Lower]; pronoun {
+		return true
+	}
+	if i+1 < len(tokens) {
+		next := tokens[i+1].Lower
+		if _, pronoun := subjectPronouns[next]; pronoun || determiners[next] || notSubject[next] {
+			return false
+		}
+	}
+	for _, t := range tokens[i+1:] {
+		if isAuxiliary(t.Lower) || modals[t.Lower] {
+			return false
+		}
+	}
+	return true
+}
+
+// subjunctiveWere reports whether "were" after the subject at start is the
+// subjunctive of "if" or "wish"
+func subjunctiveWere(tokens []token, start int) bool {
+	for _, t := range tokens[:start] {
+		switch t.Lower {
+		case "if", "wish", "wished", "though":
+			return true
+		}
+	}
+	return false
+}
+
+// subjectText returns the subject as written, e.g. "My brother"
+func subjectText(doc *document, tokens []token, s subject) string {
+	return doc.text[tokens[s.start].Start:tokens[s.head].End]
+}
@@ -0,0 +1,79 @@
+package rules
+
+// ahoCorasick finds every keyword occurring in a text in a single pass.
+// Keywords and text are compared byte by byte; callers lower-case both.
+type ahoCorasick struct {
+	nodes []acNode
+}
+
+type acNode struct {
+	next   map[byte]int32
+	fail   int32
+	output []int32 // Keyword indexes ending at this node, including via fail links
+}
+
+// newAhoCorasick builds the automaton for keywords
+func newAhoCorasick(keywords []string) *ahoCorasick {
+	ac := &ahoCorasick{nodes: []acNode{{next: make(map[byte]int32)}}}
+
+	for i, keyword := range keywords {
+		node := int32(0)
+		for j := 0; j < len(keyword); j++ {
+			child, ok := ac.nodes[node].next[keyword[j]]
+			if !ok {
+				child = int32(len(ac.nodes))
+				ac.nodes = append(ac.nodes, acNode{next: make(map[byte]int32)})
+				ac.nodes[node].next[keyword[j]] = child
+			}
+			node = child
+		}
+		ac.nodes[node].output = append(ac.nodes[node].output, int32(i))
+	}
+
+	// Breadth-first, so a node's fail target is finished before the node
+	queue := make([]int32, 0, len(ac.nodes))
+	for _, child := range ac.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for b, child := range ac.nodes[node].next {
+			fail := ac.nodes[node].fail
+			for fail != 0 {
+				if _, ok := ac.nodes[fail].next[b]; ok {
+					break
+				}
+				fail = ac.nodes[fail].fail
+			}
+			if target, ok := ac.nodes[fail].next[b]; ok && target != child {
+				ac.nodes[child].fail = target
+			}
+			ac.nodes[child].output = append(ac.nodes[child].output, ac.nodes[ac.nodes[child].fail].output...)
+			queue = append(queue, child)
+		}
+	}
+
+	return ac
+}
+
+// scan calls found with the index of every keyword occurrence in text
+func (ac *ahoCorasick) scan(text string, found func(keyword int32)) {
+	node := int32(0)
+	for i := 0; i < len(text); i++ {
+		b := text[i]
+		for {
+			if next, ok := ac.nodes[node].next[b]; ok {
+				node = next
+				break
+			}
+			if node == 0 {
+				break
+			}
+			node = ac.nodes[node].fail
+		}
+		for _, keyword := range ac.nodes[node].output {
+			found(keyword)
+		}
+	}
+}
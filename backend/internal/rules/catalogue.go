@@ -0,0 +1,117 @@
+package rules
+
+import "strings"
+
+// Level is a CEFR proficiency level
+type Level string
+
+// CEFR levels from beginner to proficient
+const (
+	LevelA1 Level = "A1"
+	LevelA2 Level = "A2"
+	LevelB1 Level = "B1"
+	LevelB2 Level = "B2"
+	LevelC1 Level = "C1"
+	LevelC2 Level = "C2"
+)
+
+var levelRanks = map[Level]int{
+	LevelA1: 1,
+	LevelA2: 2,
+	LevelB1: 3,
+	LevelB2: 4,
+	LevelC1: 5,
+	LevelC2: 6,
+}
+
+// Valid reports whether l is one of the six CEFR levels
+func (l Level) Valid() bool {
+	return levelRanks[l] != 0
+}
+
+// RuleInfo is the public description of a rule, as shown in the catalogue
+type RuleInfo struct {
+	ID          string    `json:"id"`
+	ErrorType   string    `json:"error_type"`
+	Category    string    `json:"category"`
+	Severity    Severity  `json:"severity"`
+	Level       Level     `json:"level"`
+	Tags        []string  `json:"tags"`
+	Pack        string    `json:"pack,omitempty"`
+	Description string    `json:"description"`
+	Lesson      string    `json:"lesson"`
+	Examples    []Example `json:"examples"`
+	Confidence  float64   `json:"confidence"`
+	OptIn       bool      `json:"opt_in,omitempty"` // Only runs in profiles that enable it
+}
+
+// Info returns the catalogue entry for the rule
+func (r *GrammarRule) Info() RuleInfo {
+	info := RuleInfo{
+		ID:          r.ID,
+		ErrorType:   r.ErrorType,
+		Category:    r.Category,
+		Severity:    r.Severity,
+		Level:       r.Level,
+		Tags:        r.Tags,
+		Pack:        r.Pack,
+		Description: r.Description,
+		Lesson:      r.Lesson,
+		Examples:    r.Examples,
+		Confidence:  r.Confidence,
+		OptIn:       r.OptIn,
+	}
+	if info.Tags == nil {
+		info.Tags = []string{}
+	}
+	if info.Examples == nil {
+		info.Examples = []Example{}
+	}
+	return info
+}
+
+// CatalogueFilter narrows the catalogue; empty fields match every rule
+type CatalogueFilter struct {
+	ErrorType string // Compared case-insensitively, e.g. "tense error"
+	Category  string
+	Level     Level
+	Tag       string
+	Pack      string
+}
+
+// Catalogue returns the entries of the rules matching filter, in rule order
+func (rs *RuleSet) Catalogue(filter CatalogueFilter) []RuleInfo {
+	entries := make([]RuleInfo, 0)
+	for i := range rs.Rules {
+		rule := &rs.Rules[i]
+		if filter.matches(rule) {
+			entries = append(entries, rule.Info())
+		}
+	}
+	return entries
+}
+
+// matches reports whether the rule passes every set field of the filter
+func (f CatalogueFilter) matches(rule *GrammarRule) bool {
+	if f.ErrorType != "" && !strings.EqualFold(f.ErrorType, rule.ErrorType) {
+		return false
+	}
+	if f.Category != "" && f.Category != rule.Category {
+		return false
+	}
+	if f.Level != "" && f.Level != rule.Level {
+		return false
+	}
+	if f.Pack != "" && f.Pack != rule.Pack {
+		return false
+	}
+	if f.Tag != "" {
+		for _, tag := range rule.Tags {
+			if tag == f.Tag {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
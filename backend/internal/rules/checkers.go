@@ -0,0 +1,178 @@
+package rules
+
+import (
+	"regexp"
+	"strings"
+)
+
+// checkFunc finds the errors a rule cannot express as a single regex.
+// It returns matches in document coordinates with their corrections set.
+type checkFunc func(r *GrammarRule, doc *document) []Match
+
+// checkers are the Go checkers a rule file can refer to with "checker: name"
+var checkers = map[string]checkFunc{
+	"tense_past":     checkPastTense,
+	"tense_future":   checkFutureTense,
+	"tense_duration": checkDurationTense,
+	"agreement_verb": checkVerbAgreement,
+	"agreement_be":   checkBeAgreement,
+}
+
+// token is a word inside a document, without surrounding punctuation
+type token struct {
+	Start int
+	End   int
+	Lower string // Lower-case text with curly apostrophes straightened
+}
+
+var tokenPattern = regexp.MustCompile(`[A-Za-z]+(?:['’][A-Za-z]+)*`)
+
+// tokenList returns the words of the document, computed once
+func (d *document) tokenList() []token {
+	if d.tokens == nil {
+		d.tokens = make([]token, 0)
+		for _, loc := range tokenPattern.FindAllStringIndex(d.text, -1) {
+			lower := strings.ToLower(strings.ReplaceAll(d.text[loc[0]:loc[1]], "’", "'"))
+			d.tokens = append(d.tokens, token{Start: loc[0], End: loc[1], Lower: lower})
+		}
+	}
+	return d.tokens
+}
+
+// tokensIn returns the tokens inside a segment
+func (d *document) tokensIn(segment Segment) []token {
+	tokens := d.tokenList()
+	from, to := len(tokens), len(tokens)
+	for i, t := range tokens {
+		if t.Start >= segment.Start && from == len(tokens) {
+			from = i
+		}
+		if t.Start >= segment.End {
+			to = i
+			break
+		}
+	}
+	if from > to {
+		from = to
+	}
+	return tokens[from:to]
+}
+
+// wordMatch builds a match replacing the text between two tokens
+func (r *GrammarRule) wordMatch(doc *document, first, last token, replacement, description string) Match {
+	start, end := first.Start, last.End
+	wordStart, wordEnd := wordRange(doc.words, start, end)
+	m := Match{
+		Rule:        r,
+		Start:       start,
+		End:         end,
+		WordStart:   wordStart,
+		WordEnd:     wordEnd,
+		Text:        doc.text[start:end],
+		Description: description,
+	}
+	m.Replacement = matchCase(m.Text, replacement)
+	m.Edits = buildEdits(doc.text, start, end, m.Replacement)
+	return m
+}
+
+// Person is the grammatical person and number of a subject
+type Person int
+
+const (
+	UnknownPerson Person = iota
+	FirstSingular        // I
+	ThirdSingular        // he, she, it, a singular noun
+	Plural               // you, we, they, a plural noun
+)
+
+// subjectPronouns maps subject pronouns to their person
+var subjectPronouns = map[string]Person{
+	"i": FirstSingular, "he": ThirdSingular, "she": ThirdSingular, "it": ThirdSingular,
+	"you": Plural, "we": Plural, "they": Plural,
+	"everyone": ThirdSingular, "everybody": ThirdSingular, "someone": ThirdSingular,
+	"somebody": ThirdSingular, "anyone": ThirdSingular, "anybody": ThirdSingular,
+	"nobody": ThirdSingular, "this": ThirdSingular, "that": ThirdSingular,
+	"these": Plural, "those": Plural,
+}
+
+// notSubject lists words that cannot end a subject, so a verb after them
+// is not a finite verb with its own subject: "to go", "will go", "the work"
+var notSubject = wordSet(`
+	a an the my your his her its our their some any every each no
+	to of in on at for with from by about into onto over after before during since
+	until till like as than through without under between
+	will would can could shall should may might must do does did don't doesn't didn't
+	let let's have has had is am are was were be been being
+	and or but so because if when while whether which who whom whose where what why how
+	me him us them not
+	yesterday today tomorrow tonight week month year ago last next
+`)
+
+// adverbs can sit between a subject and its verb: "I also went"
+var adverbs = wordSet(`also just only really finally still already never always usually often even actually then again sometimes
+	probably definitely generally mostly rarely seldom hardly almost`)
+
+// irregularPlurals are plural nouns without a final -s
+var irregularPlurals = wordSet(`people children men women police feet teeth mice`)
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+// subjectBefore finds the subject of a verb at tokens[i] by looking at the
+// words just before it. ok is false when the word before the verb cannot
+// be the end of a subject, e.g. "to" or a modal.
+func subjectBefore(tokens []token, i int) (person Person, ok bool) {
+	j := i - 1
+	for j >= 0 && adverbs[tokens[j].Lower] {
+		j--
+	}
+	if j < 0 {
+		return UnknownPerson, false
+	}
+
+	word := tokens[j].Lower
+	if person, found := subjectPronouns[word]; found {
+		// "you and I", "my brother and I"
+		if j >= 2 && tokens[j-1].Lower == "and" {
+			return Plural, true
+		}
+		return person, true
+	}
+	if notSubject[word] {
+		return UnknownPerson, false
+	}
+	if _, _, isVerb := LookupVerb(word); isVerb {
+		return UnknownPerson, false
+	}
+
+	if j >= 2 && tokens[j-1].Lower == "and" {
+		return Plural, true
+	}
+	if isPluralNoun(word) {
+		return Plural, true
+	}
+	return ThirdSingular, true
+}
+
+// isPluralNoun guesses from the spelling whether a noun is plural
+func isPluralNoun(word string) bool {
+	if irregularPlurals[word] {
+		return true
+	}
+	return strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") &&
+		!strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is") && len(word) > 3
+}
+
+// haveFor returns "has" or "have" for the subject
+func haveFor(person Person) string {
+	if person == ThirdSingular {
+		return "has"
+	}
+	return "have"
+}
@@ -0,0 +1,190 @@
+package rules
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Edit is a single change to the original text: at byte offset Position,
+// remove Delete and put Insert in its place. Edits from a match never
+// overlap and are ordered by position, so clients can render them as a diff.
+type Edit struct {
+	Position int    `json:"position"`
+	Delete   string `json:"delete"`
+	Insert   string `json:"insert"`
+}
+
+// Diff returns the word-level edits that turn original into corrected.
+// It is meant for corrections that do not come from a rule, e.g. the LLM.
+func Diff(original, corrected string) []Edit {
+	return buildEdits(original, 0, len(original), corrected)
+}
+
+// DiffSpan returns the word-level edits that replace text[start:end] with replacement
+func DiffSpan(text string, start, end int, replacement string) []Edit {
+	return buildEdits(text, start, end, replacement)
+}
+
+// wordSpan is a word with its byte offsets in the original text
+type wordSpan struct {
+	start, end int
+	text       string
+}
+
+// buildEdits turns the rewrite of text[start:end] into word-level edits.
+// Words the replacement keeps are left alone, so casing and punctuation
+// outside the changed words survive untouched.
+func buildEdits(text string, start, end int, replacement string) []Edit {
+	original := make([]wordSpan, 0)
+	for _, loc := range wordSpans.FindAllStringIndex(text[start:end], -1) {
+		original = append(original, wordSpan{start + loc[0], start + loc[1], text[start+loc[0] : start+loc[1]]})
+	}
+	replaced := strings.Fields(replacement)
+
+	if len(original) == 0 {
+		if text[start:end] == replacement {
+			return nil
+		}
+		return []Edit{{Position: start, Delete: text[start:end], Insert: replacement}}
+	}
+
+	edits := make([]Edit, 0, 1)
+	i, j := 0, 0
+	for _, pair := range alignWords(original, replaced) {
+		if pair[0] > i || pair[1] > j {
+			edits = append(edits, wordEdit(text, original, i, pair[0], replaced[j:pair[1]]))
+		}
+		i, j = pair[0]+1, pair[1]+1
+	}
+	if i < len(original) || j < len(replaced) {
+		edits = append(edits, wordEdit(text, original, i, len(original), replaced[j:]))
+	}
+
+	return edits
+}
+
+// alignWords returns the index pairs of the longest common word subsequence
+func alignWords(original []wordSpan, replaced []string) [][2]int {
+	n, m := len(original), len(replaced)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if original[i].text == replaced[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	pairs := make([][2]int, 0, lcs[0][0])
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case original[i].text == replaced[j]:
+			pairs = append(pairs, [2]int{i, j})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
+
+// wordEdit builds the edit replacing original[from:to] with words
+func wordEdit(text string, original []wordSpan, from, to int, words []string) Edit {
+	insert := strings.Join(words, " ")
+
+	switch {
+	case from < to && len(words) > 0:
+		// Replace the words, keeping the spacing around them
+		return Edit{
+			Position: original[from].start,
+			Delete:   text[original[from].start:original[to-1].end],
+			Insert:   insert,
+		}
+
+	case from < to:
+		// Pure deletion, take the neighbouring space with it
+		start, end := deletionRange(text, original[from].start, original[to-1].end)
+		return Edit{Position: start, Delete: text[start:end]}
+
+	case from < len(original):
+		// Pure insertion before an existing word
+		return Edit{Position: original[from].start, Insert: insert + " "}
+
+	default:
+		// Pure insertion after the last word
+		last := original[len(original)-1].end
+		return Edit{Position: last, Insert: " " + insert}
+	}
+}
+
+// deletionRange widens a deleted range so removing a word leaves neither
+// a double space nor an orphaned comma, e.g. "for, you know, two years"
+func deletionRange(text string, start, end int) (int, int) {
+	before := strings.TrimRight(text[:start], " ")
+	if strings.HasSuffix(before, ",") && strings.HasPrefix(text[end:], ",") {
+		end++
+	}
+
+	switch {
+	case end < len(text) && text[end] == ' ':
+		for end < len(text) && text[end] == ' ' {
+			end++
+		}
+	case start > 0 && text[start-1] == ' ':
+		for start > 0 && text[start-1] == ' ' {
+			start--
+		}
+	}
+	return start, end
+}
+
+// applyEdits rewrites text with edits that are sorted and non-overlapping
+func applyEdits(text string, edits []Edit) string {
+	var b strings.Builder
+	last := 0
+	for _, edit := range edits {
+		b.WriteString(text[last:edit.Position])
+		b.WriteString(edit.Insert)
+		last = edit.Position + len(edit.Delete)
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// matchCase adapts the casing of a replacement to the text it replaces:
+// a fully upper-case match gets an upper-case replacement, and a match
+// starting with a capital starts its replacement with one. Casing is only
+// ever raised, so a template such as "I have" keeps its capital I.
+func matchCase(matched, replacement string) string {
+	letters, upper := 0, 0
+	for _, r := range matched {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+
+	if letters > 1 && upper == letters {
+		return strings.ToUpper(replacement)
+	}
+
+	first, _ := utf8.DecodeRuneInString(matched)
+	if unicode.IsUpper(first) {
+		r, size := utf8.DecodeRuneInString(replacement)
+		if unicode.IsLower(r) {
+			return string(unicode.ToUpper(r)) + replacement[size:]
+		}
+	}
+	return replacement
+}
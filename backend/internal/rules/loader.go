@@ -0,0 +1,382 @@
+package rules
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/strictyaml"
+)
+
+//go:embed data/*.yaml
+var defaultRules embed.FS
+
+// RuleFile is the on-disk format of a YAML or JSON rule file
+type RuleFile struct {
+	Version  int           `yaml:"version" json:"version"`
+	Pack     *PackSpec     `yaml:"pack" json:"pack"` // Set for native-language packs, nil for base rules
+	Rules    []RuleSpec    `yaml:"rules" json:"rules"`
+	Profiles []ProfileSpec `yaml:"profiles" json:"profiles"`
+}
+
+// RuleSpec is the declarative form of a GrammarRule
+type RuleSpec struct {
+	ID          string    `yaml:"id" json:"id"`
+	Pattern     string    `yaml:"pattern" json:"pattern"`
+	ErrorType   string    `yaml:"error_type" json:"error_type"`
+	Category    string    `yaml:"category" json:"category"`
+	Severity    Severity  `yaml:"severity" json:"severity"`
+	Level       Level     `yaml:"level" json:"level"`
+	Tags        []string  `yaml:"tags" json:"tags"`
+	Description string    `yaml:"description" json:"description"`
+	Lesson      string    `yaml:"lesson" json:"lesson"`
+	Replacement *string   `yaml:"replacement" json:"replacement"` // nil flags without rewriting, "" deletes the match
+	Examples    []Example `yaml:"examples" json:"examples"`
+	Enabled     *bool     `yaml:"enabled" json:"enabled"`       // Defaults to true
+	OptIn       bool      `yaml:"opt_in" json:"opt_in"`         // Only runs in profiles that enable it
+	Confidence  float64   `yaml:"confidence" json:"confidence"` // Defaults to DefaultConfidence
+
+	Exceptions      []string `yaml:"exceptions" json:"exceptions"`
+	Scope           Scope    `yaml:"scope" json:"scope"`   // Defaults to text
+	Window          int      `yaml:"window" json:"window"` // Required with scope window
+	CounterExamples []string `yaml:"counter_examples" json:"counter_examples"`
+	Checker         string   `yaml:"checker" json:"checker"` // Go checker producing the matches, e.g. tense_past
+}
+
+// DefaultConfidence is the confidence of rules that do not set one
+const DefaultConfidence = 0.95
+
+var (
+	ruleIDPattern    = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+	templateGroupRef = regexp.MustCompile(`\$(\{\w+\}|\w+)`)
+)
+
+// ruleFileExtensions lists the file types LoadDir picks up
+var ruleFileExtensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// LoadDefault compiles the rule files built into the binary
+func LoadDefault() (*RuleSet, error) {
+	return loadFS(defaultRules, "data", "built-in")
+}
+
+// LoadDir compiles every rule file in dir into a single rule set. Any invalid
+// file fails the whole load so a bad edit can never produce a partial set.
+func LoadDir(dir string) (*RuleSet, error) {
+	return loadFS(os.DirFS(dir), ".", dir)
+}
+
+func loadFS(fsys fs.FS, dir string, source string) (*RuleSet, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := make([]GrammarRule, 0)
+	profileSpecs := make([]ProfileSpec, 0)
+	packs := make([]*Pack, 0)
+	declared := make(map[string]bool) // Rule IDs including disabled rules
+	files := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !ruleFileExtensions[filepath.Ext(entry.Name())] {
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := ParseRuleFile(entry.Name(), data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+
+		fileRules, err := file.Compile()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+
+		if file.Pack != nil {
+			pack, err := file.Pack.Compile()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+			}
+			for i := range fileRules {
+				fileRules[i].Pack = pack.Name
+			}
+			packs = append(packs, pack)
+		}
+
+		compiled = append(compiled, fileRules...)
+		profileSpecs = append(profileSpecs, file.Profiles...)
+		for _, spec := range file.Rules {
+			declared[spec.ID] = true
+		}
+		files++
+	}
+
+	if files == 0 {
+		return nil, fmt.Errorf("no rule files found in %s", source)
+	}
+
+	profiles := make([]*Profile, 0, len(profileSpecs))
+	for _, spec := range profileSpecs {
+		profile, err := spec.Compile(declared)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		profiles = append(profiles, profile)
+	}
+
+	rs, err := NewRuleSet(compiled, profiles, packs, source)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	return rs, nil
+}
+
+// ParseRuleFile decodes a rule file, picking the format from the file name.
+// Unknown fields are rejected so typos don't silently drop settings.
+func ParseRuleFile(name string, data []byte) (*RuleFile, error) {
+	var file RuleFile
+
+	switch filepath.Ext(name) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return nil, err
+		}
+	case ".yaml", ".yml":
+		if err := strictyaml.Decode(data, &file); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported rule file type %q", filepath.Ext(name))
+	}
+
+	if file.Version != 1 {
+		return nil, fmt.Errorf("unsupported rule file version %d", file.Version)
+	}
+
+	return &file, nil
+}
+
+// Compile validates every spec in the file and returns the enabled rules
+func (f *RuleFile) Compile() ([]GrammarRule, error) {
+	compiled := make([]GrammarRule, 0, len(f.Rules))
+	var errs []error
+
+	for i, spec := range f.Rules {
+		rule, err := spec.Compile()
+		if err != nil {
+			id := spec.ID
+			if id == "" {
+				id = "#" + strconv.Itoa(i+1)
+			}
+			errs = append(errs, fmt.Errorf("rule %s: %w", id, err))
+			continue
+		}
+
+		if spec.Enabled == nil || *spec.Enabled {
+			compiled = append(compiled, rule)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return compiled, nil
+}
+
+// Compile validates the spec and turns it into a GrammarRule
+func (spec RuleSpec) Compile() (GrammarRule, error) {
+	if !ruleIDPattern.MatchString(spec.ID) {
+		return GrammarRule{}, fmt.Errorf("id must be UPPER_SNAKE_CASE")
+	}
+	if spec.ErrorType == "" {
+		return GrammarRule{}, fmt.Errorf("error_type is required")
+	}
+	if !categoryPattern.MatchString(spec.Category) {
+		return GrammarRule{}, fmt.Errorf("category must be lower-case-with-dashes")
+	}
+	if spec.Severity.Rank() == 0 {
+		return GrammarRule{}, fmt.Errorf("severity must be critical, major, minor or style")
+	}
+	if !spec.Level.Valid() {
+		return GrammarRule{}, fmt.Errorf("level must be a CEFR level, A1 to C2")
+	}
+	for _, tag := range spec.Tags {
+		if !categoryPattern.MatchString(tag) {
+			return GrammarRule{}, fmt.Errorf("tag %q must be lower-case-with-dashes", tag)
+		}
+	}
+	if spec.Description == "" {
+		return GrammarRule{}, fmt.Errorf("description is required")
+	}
+	if spec.Pattern == "" {
+		return GrammarRule{}, fmt.Errorf("pattern is required")
+	}
+
+	pattern, err := regexp.Compile(spec.Pattern)
+	if err != nil {
+		return GrammarRule{}, fmt.Errorf("pattern: %w", err)
+	}
+
+	rule := GrammarRule{
+		ID:              spec.ID,
+		Pattern:         pattern,
+		ErrorType:       spec.ErrorType,
+		Category:        spec.Category,
+		Severity:        spec.Severity,
+		Description:     spec.Description,
+		Examples:        spec.Examples,
+		Level:           spec.Level,
+		Tags:            spec.Tags,
+		Lesson:          spec.Lesson,
+		Scope:           spec.Scope,
+		Window:          spec.Window,
+		CounterExamples: spec.CounterExamples,
+		Confidence:      spec.Confidence,
+		OptIn:           spec.OptIn,
+	}
+
+	switch {
+	case rule.Confidence == 0:
+		rule.Confidence = DefaultConfidence
+	case rule.Confidence < 0 || rule.Confidence > 1:
+		return GrammarRule{}, fmt.Errorf("confidence must be between 0 and 1")
+	}
+
+	switch rule.Scope {
+	case "":
+		rule.Scope = ScopeText
+	case ScopeText, ScopeSentence, ScopeClause:
+	case ScopeWindow:
+		if rule.Window <= 0 {
+			return GrammarRule{}, fmt.Errorf("scope window needs a positive window")
+		}
+	default:
+		return GrammarRule{}, fmt.Errorf("unknown scope %q", rule.Scope)
+	}
+	if rule.Window != 0 && rule.Scope != ScopeWindow {
+		return GrammarRule{}, fmt.Errorf("window is only valid with scope window")
+	}
+
+	for _, exception := range spec.Exceptions {
+		compiled, err := regexp.Compile(exception)
+		if err != nil {
+			return GrammarRule{}, fmt.Errorf("exception: %w", err)
+		}
+		rule.Exceptions = append(rule.Exceptions, compiled)
+	}
+
+	if spec.Checker != "" {
+		check, ok := checkers[spec.Checker]
+		if !ok {
+			return GrammarRule{}, fmt.Errorf("unknown checker %q", spec.Checker)
+		}
+		if spec.Replacement != nil {
+			return GrammarRule{}, fmt.Errorf("replacement cannot be used with a checker")
+		}
+		if spec.Scope != "" {
+			return GrammarRule{}, fmt.Errorf("scope cannot be used with a checker")
+		}
+		rule.Checker = spec.Checker
+		rule.check = check
+		rule.Rewrites = true
+	}
+
+	if spec.Replacement != nil {
+		if err := checkTemplate(pattern, *spec.Replacement); err != nil {
+			return GrammarRule{}, fmt.Errorf("replacement: %w", err)
+		}
+		rule.Replacement = *spec.Replacement
+		rule.Rewrites = true
+	}
+
+	if err := rule.checkExamples(); err != nil {
+		return GrammarRule{}, err
+	}
+
+	return rule, nil
+}
+
+// checkTemplate makes sure every group referenced by the template exists
+func checkTemplate(pattern *regexp.Regexp, template string) error {
+	names := pattern.SubexpNames()
+	for _, ref := range templateGroupRef.FindAllStringSubmatch(template, -1) {
+		group := strings.Trim(ref[1], "{}")
+		if n, err := strconv.Atoi(group); err == nil {
+			if n > pattern.NumSubexp() {
+				return fmt.Errorf("group $%d does not exist in pattern", n)
+			}
+			continue
+		}
+		found := false
+		for _, name := range names {
+			if name == group {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("group %q does not exist in pattern", group)
+		}
+	}
+	return nil
+}
+
+// checkExamples verifies the rule against its own examples
+func (r *GrammarRule) checkExamples() error {
+	for _, example := range r.Examples {
+		if example.Bad == "" || example.Good == "" {
+			return fmt.Errorf("examples need both bad and good sentences")
+		}
+		if len(r.matches(newDocument(example.Bad))) == 0 {
+			return fmt.Errorf("rule does not match example %q", example.Bad)
+		}
+		if len(r.matches(newDocument(example.Good))) > 0 {
+			return fmt.Errorf("rule matches corrected example %q", example.Good)
+		}
+		if r.Rewrites {
+			if got := r.Correction(example.Bad); got != example.Good {
+				return fmt.Errorf("example %q corrects to %q, want %q", example.Bad, got, example.Good)
+			}
+		}
+	}
+
+	for _, sentence := range r.CounterExamples {
+		if matches := r.matches(newDocument(sentence)); len(matches) > 0 {
+			return fmt.Errorf("rule matches counter example %q at %q", sentence, matches[0].Text)
+		}
+	}
+	return nil
+}
+
+// ruleFileNames returns the sorted rule file names in dir
+func ruleFileNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && ruleFileExtensions[filepath.Ext(entry.Name())] {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,208 @@
+package rules
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// maxAnchors caps the literals kept for one rule; a pattern needing more
+// alternatives than this is cheaper to run unconditionally
+const maxAnchors = 64
+
+// matcher screens text before any regex runs. Each rule is reduced to a set
+// of anchor literals, one of which every match must contain, and a single
+// Aho-Corasick pass over the text finds the rules whose anchors occur.
+// Only those candidates run their regexes.
+type matcher struct {
+	keywords *ahoCorasick
+	rulesFor [][]int32 // Rule indexes by keyword index
+	always   []int32   // Rules without anchors, always candidates
+	rules    int
+}
+
+// newMatcher indexes the anchors of every rule
+func newMatcher(rules []GrammarRule) *matcher {
+	m := &matcher{rules: len(rules)}
+	keywordIndex := make(map[string]int32)
+	keywords := make([]string, 0)
+
+	for i := range rules {
+		anchors := patternAnchors(rules[i].Pattern)
+		if len(anchors) == 0 {
+			m.always = append(m.always, int32(i))
+			continue
+		}
+		for _, anchor := range anchors {
+			k, ok := keywordIndex[anchor]
+			if !ok {
+				k = int32(len(keywords))
+				keywordIndex[anchor] = k
+				keywords = append(keywords, anchor)
+				m.rulesFor = append(m.rulesFor, nil)
+			}
+			m.rulesFor[k] = append(m.rulesFor[k], int32(i))
+		}
+	}
+
+	m.keywords = newAhoCorasick(keywords)
+	return m
+}
+
+// candidates reports, by rule index, which rules could match text
+func (m *matcher) candidates(text string) []bool {
+	candidate := make([]bool, m.rules)
+	for _, i := range m.always {
+		candidate[i] = true
+	}
+	m.keywords.scan(strings.ToLower(text), func(keyword int32) {
+		for _, i := range m.rulesFor[keyword] {
+			candidate[i] = true
+		}
+	})
+	return candidate
+}
+
+// CandidateRules returns the rules whose anchor words occur in text, in
+// rule order. Rules not returned cannot match text.
+func (rs *RuleSet) CandidateRules(text string) []*GrammarRule {
+	candidate := rs.matcher.candidates(text)
+	rules := make([]*GrammarRule, 0)
+	for i := range rs.Rules {
+		if candidate[i] {
+			rules = append(rules, &rs.Rules[i])
+		}
+	}
+	return rules
+}
+
+// patternAnchors returns lower-case literals one of which occurs in every
+// match of pattern, or nil if the pattern has no usable literals
+func patternAnchors(pattern *regexp.Regexp) []string {
+	re, err := syntax.Parse(pattern.String(), syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	anchors, ok := requiredLiterals(re.Simplify())
+	if !ok || len(anchors) > maxAnchors {
+		return nil
+	}
+	return anchors
+}
+
+// requiredLiterals returns a set of literals such that every match of re
+// contains at least one of them. ok is false when there is no such set.
+func requiredLiterals(re *syntax.Regexp) (literals []string, ok bool) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return []string{strings.ToLower(string(re.Rune))}, true
+
+	case syntax.OpCharClass:
+		return classLiterals(re.Rune)
+
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredLiterals(re.Sub[0])
+
+	case syntax.OpRepeat:
+		if re.Min == 0 {
+			return nil, false
+		}
+		return requiredLiterals(re.Sub[0])
+
+	case syntax.OpAlternate:
+		seen := make(map[string]bool)
+		for _, sub := range re.Sub {
+			subLiterals, ok := requiredLiterals(sub)
+			if !ok {
+				return nil, false
+			}
+			for _, literal := range subLiterals {
+				if !seen[literal] {
+					seen[literal] = true
+					literals = append(literals, literal)
+				}
+			}
+		}
+		return literals, true
+
+	case syntax.OpConcat:
+		return concatLiterals(re.Sub)
+	}
+
+	return nil, false
+}
+
+// concatLiterals picks the most selective literal set among the parts of a
+// concatenation. Adjacent literals are joined first, so "I has" is one
+// anchor rather than "I" and " has".
+func concatLiterals(subs []*syntax.Regexp) ([]string, bool) {
+	var best []string
+	var run strings.Builder
+
+	consider := func(literals []string) {
+		if anchorScore(literals) > anchorScore(best) {
+			best = literals
+		}
+	}
+	flush := func() {
+		if run.Len() > 0 {
+			consider([]string{run.String()})
+			run.Reset()
+		}
+	}
+
+	for _, sub := range subs {
+		switch sub.Op {
+		case syntax.OpLiteral:
+			run.WriteString(strings.ToLower(string(sub.Rune)))
+		case syntax.OpWordBoundary, syntax.OpBeginLine, syntax.OpEndLine,
+			syntax.OpBeginText, syntax.OpEndText, syntax.OpEmptyMatch:
+			// Zero-width, so the literals either side are still adjacent
+		default:
+			flush()
+			if literals, ok := requiredLiterals(sub); ok {
+				consider(literals)
+			}
+		}
+	}
+	flush()
+
+	return best, best != nil
+}
+
+// anchorScore ranks literal sets by their shortest literal, so a set that
+// can only be satisfied by long strings wins; fewer literals break ties
+func anchorScore(literals []string) int {
+	if len(literals) == 0 {
+		return 0
+	}
+	shortest := len(literals[0])
+	for _, literal := range literals[1:] {
+		shortest = min(shortest, len(literal))
+	}
+	return shortest*(maxAnchors+1) + maxAnchors - min(len(literals), maxAnchors)
+}
+
+// classLiterals expands a small character class such as [Ii] into single
+// character literals
+func classLiterals(ranges []rune) ([]string, bool) {
+	seen := make(map[rune]bool)
+	literals := make([]string, 0)
+	for i := 0; i+1 < len(ranges); i += 2 {
+		if ranges[i+1]-ranges[i] > 8 {
+			return nil, false
+		}
+		for r := ranges[i]; r <= ranges[i+1]; r++ {
+			lower := unicode.ToLower(r)
+			if !seen[lower] {
+				seen[lower] = true
+				literals = append(literals, string(lower))
+			}
+		}
+		if len(literals) > 8 {
+			return nil, false
+		}
+	}
+	return literals, len(literals) > 0
+}
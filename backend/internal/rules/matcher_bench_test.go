@@ -0,0 +1,120 @@
+package rules
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// benchUtterances are typical transcript chunks, with and without errors
+var benchUtterances = []string{
+	"I has a book and yesterday I go to the market",
+	"Tell me about yourself and your experience with distributed systems",
+	"umm so basically I was working on the payment team for two years",
+	"We discussed the design with the team and they is happy with it",
+	"My good name is Rahul and I am from Delhi",
+	"I think the main challenge was scaling the database during the sale",
+}
+
+// benchVocabulary supplies the words of generated rules
+var benchVocabulary = strings.Fields(`
+	able about above accept account across action actually address admit adult
+	affect after again against agency agent agree ahead allow almost alone along
+	already also although always among amount analysis animal another answer
+	anyone anything appear apply approach area argue around arrive article artist
+	assume attack attention attorney audience author avoid away baby back bad bag
+	ball bank base beat beautiful because become bed before begin behavior behind
+	believe benefit best between beyond bill billion bit black blood blue board
+	body book born both box boy break bring brother budget build building business
+	but buy call camera campaign cancer candidate capital card care career carry
+	case catch cause cell center central century certain chair challenge chance
+	change character charge check child choice choose church citizen city civil
+	claim class clear close coach cold collection college color commercial common
+	community company compare computer concern condition conference consider
+	consumer contain continue control cost could country couple course court cover
+	create crime cultural culture current customer cut dark data daughter dead deal
+	death debate decade decide decision deep defense degree democrat describe design
+	despite detail determine develop difference different difficult dinner direction
+	director discover discuss disease doctor dog door down draw dream drive drop
+	drug during early east easy economic economy edge education effect effort eight
+	either election else employee energy enjoy enough enter entire environment
+	especially establish evening event ever every evidence exactly example executive
+	exist expect experience expert explain factor fail fall family father fear
+	federal feel feeling field fight figure fill film final finally financial find
+`)
+
+var benchTimeMarkers = []string{"yesterday", "last night", "tomorrow", "next week", "ago", "recently"}
+
+// BenchmarkDetectAll measures per-call latency of the rule engine as the
+// rule set grows. The built-in rules are padded with generated rules of the
+// same shape (word pairs, alternations and "time marker ... verb" patterns)
+// up to each size, and every size is timed twice: running every regex on
+// every call, as the engine did before the keyword prefilter, and through
+// DetectAll, which only runs the candidate rules.
+func BenchmarkDetectAll(b *testing.B) {
+	base := Active()
+	rng := rand.New(rand.NewSource(1))
+
+	for _, size := range []int{50, 500, 5000} {
+		ruleSet, err := NewRuleSet(generateRules(base.Rules, size, rng), nil, base.PackList(), "generated")
+		if err != nil {
+			b.Fatalf("Failed to build %d rules: %v", size, err)
+		}
+
+		candidates := 0
+		for _, text := range benchUtterances {
+			candidates += len(ruleSet.CandidateRules(text))
+		}
+		perCall := float64(candidates) / float64(len(benchUtterances))
+
+		b.Run(fmt.Sprintf("rules=%d/all-regexes", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for j := range ruleSet.Rules {
+					ruleSet.Rules[j].Pattern.FindAllStringSubmatchIndex(benchUtterances[i%len(benchUtterances)], -1)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("rules=%d/prefiltered", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ruleSet.DetectAll(benchUtterances[i%len(benchUtterances)])
+			}
+			b.ReportMetric(perCall, "candidates/call")
+		})
+	}
+}
+
+// generateRules returns size rules: the base rules first, then generated ones
+func generateRules(base []GrammarRule, size int, rng *rand.Rand) []GrammarRule {
+	generated := make([]GrammarRule, 0, size)
+	generated = append(generated, base[:min(len(base), size)]...)
+
+	for i := len(generated); i < size; i++ {
+		var pattern string
+		switch i % 3 {
+		case 0:
+			pattern = fmt.Sprintf(`(?i)\b%s %s\b`, benchWord(rng), benchWord(rng))
+		case 1:
+			pattern = fmt.Sprintf(`(?i)\b(%s|%s|%s) %s\b`, benchWord(rng), benchWord(rng), benchWord(rng), benchWord(rng))
+		default:
+			marker := benchTimeMarkers[rng.Intn(len(benchTimeMarkers))]
+			pattern = fmt.Sprintf(`(?i)\b(%s\b.*\b)%s\b`, marker, benchWord(rng))
+		}
+
+		generated = append(generated, GrammarRule{
+			ID:          fmt.Sprintf("GENERATED_%d", i),
+			Pattern:     regexp.MustCompile(pattern),
+			ErrorType:   "Generated",
+			Category:    "generated",
+			Severity:    SeverityMinor,
+			Description: "Generated rule",
+			Scope:       ScopeText,
+		})
+	}
+	return generated
+}
+
+func benchWord(rng *rand.Rand) string {
+	return benchVocabulary[rng.Intn(len(benchVocabulary))]
+}
@@ -0,0 +1,101 @@
+package rules
+
+import (
+	"log"
+	"sync"
+)
+
+// Override changes a rule at run time without editing its file, e.g.
+// after users disputed too many of its matches
+type Override struct {
+	Confidence float64 `json:"confidence,omitempty"` // Replaces the rule's confidence when not zero
+	Disabled   bool    `json:"disabled,omitempty"`   // Stops the rule from running
+	Reason     string  `json:"reason,omitempty"`
+}
+
+var (
+	// base is the rule set as loaded; active is base with the overrides applied
+	base      *RuleSet
+	overrides map[string]Override
+	swapMu    sync.Mutex // Serialises SetActive and SetOverrides
+)
+
+// Base returns the active rule set as loaded, before overrides. It still
+// holds the rules an override disabled.
+func Base() *RuleSet {
+	swapMu.Lock()
+	defer swapMu.Unlock()
+	return base
+}
+
+// Overrides returns a copy of the overrides in effect, by rule ID
+func Overrides() map[string]Override {
+	swapMu.Lock()
+	defer swapMu.Unlock()
+
+	copied := make(map[string]Override, len(overrides))
+	for id, override := range overrides {
+		copied[id] = override
+	}
+	return copied
+}
+
+// SetOverrides replaces the overrides and applies them to the active rule
+// set. They stay in effect when the rules are reloaded; overrides for rule
+// IDs the set does not have are ignored.
+func SetOverrides(byID map[string]Override) {
+	swapMu.Lock()
+	defer swapMu.Unlock()
+
+	overrides = make(map[string]Override, len(byID))
+	for id, override := range byID {
+		overrides[id] = override
+	}
+	active.Store(base.withOverrides(overrides))
+}
+
+// withOverrides returns a copy of rs with overrides applied, or rs itself
+// if none of them concern its rules
+func (rs *RuleSet) withOverrides(byID map[string]Override) *RuleSet {
+	applies := false
+	for id := range byID {
+		if rs.Lookup(id) != nil {
+			applies = true
+			break
+		}
+	}
+	if !applies {
+		return rs
+	}
+
+	kept := make([]GrammarRule, 0, len(rs.Rules))
+	for _, rule := range rs.Rules {
+		override, ok := byID[rule.ID]
+		switch {
+		case !ok:
+		case override.Disabled:
+			continue
+		case override.Confidence > 0:
+			rule.Confidence = override.Confidence
+		}
+		kept = append(kept, rule)
+	}
+
+	profiles := make([]*Profile, 0, len(rs.Profiles))
+	for _, profile := range rs.Profiles {
+		profiles = append(profiles, profile)
+	}
+	packs := make([]*Pack, 0, len(rs.Packs))
+	for _, pack := range rs.Packs {
+		packs = append(packs, pack)
+	}
+
+	adjusted, err := NewRuleSet(kept, profiles, packs, rs.Source)
+	if err != nil {
+		// Dropping rules from a valid set cannot fail, but never lose the rules over it
+		log.Printf("Cannot apply rule overrides: %v", err)
+		return rs
+	}
+	adjusted.LoadedAt = rs.LoadedAt
+	return adjusted
+}
@@ -0,0 +1,86 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Pack is a group of rules for mistakes typical of speakers of particular
+// native languages, e.g. dropped articles for Hindi and Punjabi speakers.
+// Pack rules run on top of the base rules when the speaker's native
+// language is one of the pack's languages.
+type Pack struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Languages   []string `json:"languages"`
+	Rules       []string `json:"rules"` // IDs of the enabled rules in the pack
+}
+
+// PackSpec is the declarative form of a Pack. A rule file with a pack
+// section puts every rule in the file into that pack.
+type PackSpec struct {
+	Name        string   `yaml:"name" json:"name"`
+	Description string   `yaml:"description" json:"description"`
+	Languages   []string `yaml:"languages" json:"languages"`
+}
+
+// Compile validates the spec
+func (spec PackSpec) Compile() (*Pack, error) {
+	if !categoryPattern.MatchString(spec.Name) {
+		return nil, fmt.Errorf("pack name %q must be lower-case-with-dashes", spec.Name)
+	}
+	if len(spec.Languages) == 0 {
+		return nil, fmt.Errorf("pack %s: languages is required", spec.Name)
+	}
+	for _, language := range spec.Languages {
+		if strings.TrimSpace(language) == "" {
+			return nil, fmt.Errorf("pack %s: empty language", spec.Name)
+		}
+	}
+
+	return &Pack{
+		Name:        spec.Name,
+		Description: spec.Description,
+		Languages:   spec.Languages,
+		Rules:       make([]string, 0),
+	}, nil
+}
+
+// languageKey normalises a language name for lookups, so "hindi" and
+// "Hindi" select the same packs
+func languageKey(language string) string {
+	return strings.ToLower(strings.TrimSpace(language))
+}
+
+// PacksFor returns the packs that apply to speakers of the native language,
+// sorted by name
+func (rs *RuleSet) PacksFor(nativeLanguage string) []*Pack {
+	packs := make([]*Pack, 0)
+	for _, name := range rs.byLanguage[languageKey(nativeLanguage)] {
+		packs = append(packs, rs.Packs[name])
+	}
+	return packs
+}
+
+// PackList returns every pack sorted by name
+func (rs *RuleSet) PackList() []*Pack {
+	packs := make([]*Pack, 0, len(rs.Packs))
+	for _, pack := range rs.Packs {
+		packs = append(packs, pack)
+	}
+	sort.Slice(packs, func(i, j int) bool {
+		return packs[i].Name < packs[j].Name
+	})
+	return packs
+}
+
+// activePacks returns the set of pack names that apply to the native language
+func (rs *RuleSet) activePacks(nativeLanguage string) map[string]bool {
+	names := rs.byLanguage[languageKey(nativeLanguage)]
+	active := make(map[string]bool, len(names))
+	for _, name := range names {
+		active[name] = true
+	}
+	return active
+}
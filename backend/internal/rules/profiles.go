@@ -0,0 +1,149 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// Severity ranks how serious an error is
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical" // Breaks basic grammar, e.g. "I has"
+	SeverityMajor    Severity = "major"    // Clearly wrong, e.g. a tense error
+	SeverityMinor    Severity = "minor"    // Noticeable but easy to overlook
+	SeverityStyle    Severity = "style"    // Usage, e.g. Indianisms and redundancy
+)
+
+var severityRanks = map[Severity]int{
+	SeverityStyle:    1,
+	SeverityMinor:    2,
+	SeverityMajor:    3,
+	SeverityCritical: 4,
+}
+
+// Rank orders severities from style (1) to critical (4); unknown is 0
+func (s Severity) Rank() int {
+	return severityRanks[s]
+}
+
+// DefaultProfile is used when no profile is selected
+const DefaultProfile = "default"
+
+var categoryPattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// Profile selects which rules run, e.g. for a strict interview or a
+// casual chat where Indianisms should not be flagged
+type Profile struct {
+	Name               string   `json:"name"`
+	Description        string   `json:"description"`
+	MinSeverity        Severity `json:"min_severity"`
+	DisabledCategories []string `json:"disabled_categories"`
+	DisabledRules      []string `json:"disabled_rules"`
+	EnabledCategories  []string `json:"enabled_categories"`
+	EnabledRules       []string `json:"enabled_rules"`
+
+	disabledCategories map[string]bool
+	disabledRules      map[string]bool
+	enabledCategories  map[string]bool
+	enabledRules       map[string]bool
+}
+
+// ProfileSpec is the declarative form of a Profile in a rule file
+type ProfileSpec struct {
+	Name               string   `yaml:"name" json:"name"`
+	Description        string   `yaml:"description" json:"description"`
+	MinSeverity        Severity `yaml:"min_severity" json:"min_severity"` // Defaults to style, i.e. everything
+	DisabledCategories []string `yaml:"disabled_categories" json:"disabled_categories"`
+	DisabledRules      []string `yaml:"disabled_rules" json:"disabled_rules"`
+	EnabledCategories  []string `yaml:"enabled_categories" json:"enabled_categories"` // Turns on opt-in rules
+	EnabledRules       []string `yaml:"enabled_rules" json:"enabled_rules"`
+}
+
+// Allows reports whether the profile runs the rule. Opt-in rules only run
+// when their category or ID is enabled.
+func (p *Profile) Allows(rule *GrammarRule) bool {
+	if rule.OptIn && !p.enabledCategories[rule.Category] && !p.enabledRules[rule.ID] {
+		return false
+	}
+	return rule.Severity.Rank() >= p.MinSeverity.Rank() &&
+		!p.disabledCategories[rule.Category] &&
+		!p.disabledRules[rule.ID]
+}
+
+// Compile validates the spec against the declared rule IDs
+func (spec ProfileSpec) Compile(declared map[string]bool) (*Profile, error) {
+	if !categoryPattern.MatchString(spec.Name) {
+		return nil, fmt.Errorf("profile name %q must be lower-case-with-dashes", spec.Name)
+	}
+
+	profile := &Profile{
+		Name:               spec.Name,
+		Description:        spec.Description,
+		MinSeverity:        spec.MinSeverity,
+		DisabledCategories: spec.DisabledCategories,
+		DisabledRules:      spec.DisabledRules,
+		EnabledCategories:  spec.EnabledCategories,
+		EnabledRules:       spec.EnabledRules,
+		disabledCategories: make(map[string]bool, len(spec.DisabledCategories)),
+		disabledRules:      make(map[string]bool, len(spec.DisabledRules)),
+		enabledCategories:  make(map[string]bool, len(spec.EnabledCategories)),
+		enabledRules:       make(map[string]bool, len(spec.EnabledRules)),
+	}
+
+	if profile.MinSeverity == "" {
+		profile.MinSeverity = SeverityStyle
+	}
+	if profile.MinSeverity.Rank() == 0 {
+		return nil, fmt.Errorf("profile %s: unknown min_severity %q", spec.Name, spec.MinSeverity)
+	}
+
+	for _, category := range spec.DisabledCategories {
+		profile.disabledCategories[category] = true
+	}
+	for _, id := range spec.DisabledRules {
+		if !declared[id] {
+			return nil, fmt.Errorf("profile %s: unknown rule %s", spec.Name, id)
+		}
+		profile.disabledRules[id] = true
+	}
+	for _, category := range spec.EnabledCategories {
+		profile.enabledCategories[category] = true
+	}
+	for _, id := range spec.EnabledRules {
+		if !declared[id] {
+			return nil, fmt.Errorf("profile %s: unknown rule %s", spec.Name, id)
+		}
+		profile.enabledRules[id] = true
+	}
+
+	return profile, nil
+}
+
+// allRulesProfile is the default profile when the rule files define none
+func allRulesProfile() *Profile {
+	profile, _ := ProfileSpec{Name: DefaultProfile, Description: "Every rule"}.Compile(nil)
+	return profile
+}
+
+// Profile returns the named profile, the default profile for an empty
+// name, or nil if the rule set has no such profile
+func (rs *RuleSet) Profile(name string) *Profile {
+	if name == "" {
+		name = DefaultProfile
+	}
+	return rs.Profiles[name]
+}
+
+// ProfileList returns the profiles sorted by name
+func (rs *RuleSet) ProfileList() []*Profile {
+	profiles := make([]*Profile, 0, len(rs.Profiles))
+	for _, profile := range rs.Profiles {
+		profiles = append(profiles, profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles
+}
@@ -0,0 +1,118 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// RuleSet is an immutable, compiled collection of grammar rules
+type RuleSet struct {
+	Rules    []GrammarRule
+	Profiles map[string]*Profile
+	Packs    map[string]*Pack // Native-language packs by name
+	Source   string           // Where the rules were loaded from
+	LoadedAt time.Time        // When the rules were compiled
+
+	byID       map[string]int
+	byLanguage map[string][]string // Sorted pack names by normalised language
+	matcher    *matcher            // Prefilter picking the rules worth running on a text
+}
+
+// active is the rule set used by the package-level detection functions.
+// It is swapped as a whole on reload so readers never see a partial set.
+var active atomic.Pointer[RuleSet]
+
+func init() {
+	rs, err := LoadDefault()
+	if err != nil {
+		panic(fmt.Sprintf("rules: invalid built-in rules: %v", err))
+	}
+	SetActive(rs)
+}
+
+// Active returns the rule set currently used for detection
+func Active() *RuleSet {
+	return active.Load()
+}
+
+// SetActive atomically replaces the rule set used for detection. The
+// overrides in effect are applied to it.
+func SetActive(rs *RuleSet) {
+	swapMu.Lock()
+	defer swapMu.Unlock()
+
+	base = rs
+	active.Store(rs.withOverrides(overrides))
+}
+
+// NewRuleSet builds a rule set from compiled rules, profiles and packs,
+// rejecting duplicate IDs and rules in undeclared packs. A default profile
+// running every rule is added if missing.
+func NewRuleSet(rules []GrammarRule, profiles []*Profile, packs []*Pack, source string) (*RuleSet, error) {
+	rs := &RuleSet{
+		Rules:      rules,
+		Profiles:   make(map[string]*Profile, len(profiles)+1),
+		Packs:      make(map[string]*Pack, len(packs)),
+		Source:     source,
+		LoadedAt:   time.Now(),
+		byID:       make(map[string]int, len(rules)),
+		byLanguage: make(map[string][]string),
+	}
+
+	for _, spec := range packs {
+		// Copy so the rule list is rebuilt for this set
+		pack := *spec
+		pack.Rules = make([]string, 0)
+		if _, exists := rs.Packs[pack.Name]; exists {
+			return nil, fmt.Errorf("duplicate pack %s", pack.Name)
+		}
+		rs.Packs[pack.Name] = &pack
+		for _, language := range pack.Languages {
+			key := languageKey(language)
+			rs.byLanguage[key] = append(rs.byLanguage[key], pack.Name)
+		}
+	}
+	for _, names := range rs.byLanguage {
+		sort.Strings(names)
+	}
+
+	for i, rule := range rules {
+		if _, exists := rs.byID[rule.ID]; exists {
+			return nil, fmt.Errorf("duplicate rule id %s", rule.ID)
+		}
+		rs.byID[rule.ID] = i
+
+		if rule.Pack != "" {
+			pack, exists := rs.Packs[rule.Pack]
+			if !exists {
+				return nil, fmt.Errorf("rule %s: unknown pack %s", rule.ID, rule.Pack)
+			}
+			pack.Rules = append(pack.Rules, rule.ID)
+		}
+	}
+
+	for _, profile := range profiles {
+		if _, exists := rs.Profiles[profile.Name]; exists {
+			return nil, fmt.Errorf("duplicate profile %s", profile.Name)
+		}
+		rs.Profiles[profile.Name] = profile
+	}
+	if _, exists := rs.Profiles[DefaultProfile]; !exists {
+		rs.Profiles[DefaultProfile] = allRulesProfile()
+	}
+
+	rs.matcher = newMatcher(rules)
+
+	return rs, nil
+}
+
+// Lookup returns the rule with the given ID, or nil if there is none
+func (rs *RuleSet) Lookup(id string) *GrammarRule {
+	i, ok := rs.byID[id]
+	if !ok {
+		return nil
+	}
+	return &rs.Rules[i]
+}
@@ -0,0 +1,149 @@
+package rules
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Scope limits how much text a single rule match may span
+type Scope string
+
+const (
+	ScopeText     Scope = "text"     // The whole input, the default
+	ScopeSentence Scope = "sentence" // A single sentence
+	ScopeClause   Scope = "clause"   // A single clause, see Clauses
+	ScopeWindow   Scope = "window"   // At most GrammarRule.Window words
+)
+
+// Segment is a sentence or clause inside a larger text
+type Segment struct {
+	Start int // Byte offset of the first character
+	End   int // Byte offset just past the last character
+	Text  string
+}
+
+var (
+	sentenceEnd = regexp.MustCompile(`[.!?]+["')\]]*(\s+|$)`)
+	clauseBreak = regexp.MustCompile(`\s*(?:[,;:]|\s-\s|[—–])\s*`)
+
+	// abbreviations never end a sentence even when followed by a space
+	abbreviations = map[string]bool{
+		"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "sr": true, "jr": true,
+		"st": true, "vs": true, "etc": true, "e.g": true, "i.e": true, "no": true,
+	}
+
+	// conjunctions start a new clause when a subject follows them
+	conjunctions = map[string]bool{
+		"and": true, "but": true, "or": true, "so": true, "because": true, "although": true,
+		"though": true, "while": true, "whereas": true, "unless": true, "if": true, "when": true,
+	}
+
+	subjects = map[string]bool{
+		"i": true, "you": true, "he": true, "she": true, "it": true, "we": true, "they": true,
+		"the": true, "my": true, "his": true, "her": true, "our": true, "their": true,
+		"this": true, "that": true, "there": true,
+	}
+)
+
+// Sentences splits text at sentence-final punctuation followed by a space
+// or the end of the text. Common abbreviations such as "Dr." are skipped.
+func Sentences(text string) []Segment {
+	segments := make([]Segment, 0, 1)
+	start := 0
+	for _, loc := range sentenceEnd.FindAllStringSubmatchIndex(text, -1) {
+		words := strings.Fields(text[start:loc[0]])
+		if len(words) > 0 && abbreviations[strings.ToLower(words[len(words)-1])] && loc[1] < len(text) {
+			continue
+		}
+		segments = appendSegment(segments, text, start, loc[2])
+		start = loc[1]
+	}
+	return appendSegment(segments, text, start, len(text))
+}
+
+// Clauses splits every sentence of text into clauses. A clause ends at
+// a comma, semicolon, colon or dash, and before a conjunction that is
+// followed by a subject, as in "I go home and he has a car". The split
+// is a heuristic: it errs towards longer clauses rather than cutting
+// noun phrases such as "my brother and I".
+func Clauses(text string) []Segment {
+	segments := make([]Segment, 0, 1)
+	for _, sentence := range Sentences(text) {
+		start := sentence.Start
+		for _, loc := range clauseBreak.FindAllStringIndex(sentence.Text, -1) {
+			segments = appendConjunctionClauses(segments, text, start, sentence.Start+loc[0])
+			start = sentence.Start + loc[1]
+		}
+		segments = appendConjunctionClauses(segments, text, start, sentence.End)
+	}
+	return segments
+}
+
+// appendConjunctionClauses splits text[start:end] before conjunctions
+// that introduce a new subject and appends the pieces
+func appendConjunctionClauses(segments []Segment, text string, start, end int) []Segment {
+	words := wordSpans.FindAllStringIndex(text[start:end], -1)
+	clauseStart := start
+	for i := 1; i < len(words)-1; i++ {
+		word := strings.ToLower(text[start+words[i][0] : start+words[i][1]])
+		next := strings.ToLower(text[start+words[i+1][0] : start+words[i+1][1]])
+		if conjunctions[word] && subjects[next] {
+			segments = appendSegment(segments, text, clauseStart, start+words[i][0])
+			clauseStart = start + words[i][0]
+		}
+	}
+	return appendSegment(segments, text, clauseStart, end)
+}
+
+// appendSegment adds text[start:end] without surrounding space, if not empty
+func appendSegment(segments []Segment, text string, start, end int) []Segment {
+	for start < end && isSpace(text[start]) {
+		start++
+	}
+	for end > start && isSpace(text[end-1]) {
+		end--
+	}
+	if start == end {
+		return segments
+	}
+	return append(segments, Segment{Start: start, End: end, Text: text[start:end]})
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// document caches the word offsets and segmentation of a text so every
+// rule checked against it shares the work
+type document struct {
+	text      string
+	words     [][]int
+	sentences []Segment
+	clauses   []Segment
+	tokens    []token
+}
+
+func newDocument(text string) *document {
+	return &document{
+		text:  text,
+		words: wordSpans.FindAllStringIndex(text, -1),
+	}
+}
+
+// segments returns the units a rule with the given scope is matched in
+func (d *document) segments(scope Scope) []Segment {
+	switch scope {
+	case ScopeSentence:
+		if d.sentences == nil {
+			d.sentences = Sentences(d.text)
+		}
+		return d.sentences
+	case ScopeClause:
+		if d.clauses == nil {
+			d.clauses = Clauses(d.text)
+		}
+		return d.clauses
+	default:
+		return []Segment{{Start: 0, End: len(d.text), Text: d.text}}
+	}
+}
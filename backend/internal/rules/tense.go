@@ -0,0 +1,297 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Time markers the tense checkers pair with verbs. A clause with a present
+// marker ("today", "usually") is left alone, since it may legitimately mix
+// tenses: "Yesterday was hard, so today I go slowly".
+var (
+	weekdays = `monday|tuesday|wednesday|thursday|friday|saturday|sunday`
+	months   = `january|february|march|april|may|june|july|august|september|october|november|december`
+	numbers  = `a|an|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve|fifteen|twenty|thirty|\d+|many|several|a few|a couple of`
+	periods  = `seconds?|minutes?|hours?|days?|weeks?|months?|years?|decades?`
+
+	pastMarker = regexp.MustCompile(`(?i)\b(?:the day before yesterday|yesterday|last (?:night|week|weekend|month|year|time|summer|winter|` +
+		weekdays + `)|(?:(?:` + numbers + `) (?:` + periods + `) )?ago)\b`)
+	futureMarker = regexp.MustCompile(`(?i)\b(?:the day after tomorrow|tomorrow|next (?:week|weekend|month|year|time|summer|winter|` +
+		weekdays + `))\b`)
+	presentMarker  = regexp.MustCompile(`(?i)\b(?:today|now|nowadays|these days|currently|usually|every (?:day|week|month|year|morning|evening))\b`)
+	durationMarker = regexp.MustCompile(`(?i)\b(?:for (?:(?:` + numbers + `) (?:` + periods + `)|a long time|ages)|since (?:(?:19|20)\d\d|` +
+		months + `|` + weekdays + `|childhood|morning|yesterday|last (?:week|month|year)|then))\b`)
+
+	// sequenceWords mark a finished past inside a duration sentence:
+	// "I worked there for two years before I moved"
+	sequenceWords = regexp.MustCompile(`(?i)\b(?:before|after|until|till|then|when)\b`)
+)
+
+// statives describe states rather than actions, so they take the present
+// perfect ("have known") instead of the continuous ("have been knowing")
+var statives = wordSet(`be have know like love hate own belong want need understand believe mean prefer remember seem contain depend deserve exist`)
+
+// pastNegatives and futureNegatives rewrite negative contractions
+var (
+	pastNegatives   = map[string]string{"don't": "didn't", "doesn't": "didn't", "isn't": "wasn't", "aren't": "weren't", "can't": "couldn't", "won't": "didn't"}
+	futureNegatives = map[string]string{"didn't": "won't", "wasn't": "won't be", "weren't": "won't be", "couldn't": "won't be able to"}
+)
+
+// timeClause is a clause together with the time marker that governs it
+type timeClause struct {
+	tokens []token
+	marker string // The marker text, lower-case
+	at     int    // Byte offset of the marker, -1 when carried from an earlier clause
+	before bool   // Whether the marker comes before the clause's verbs
+}
+
+// modals start a verb phrase of their own, so a marker on the far side of
+// one belongs to that phrase: "I told him I would call him tomorrow"
+var modals = wordSet(`will would can could shall should may might must`)
+
+// subordinators open a clause inside the clause: "I wonder why they were
+// late yesterday" puts "yesterday" with "were", not with "wonder"
+var subordinators = wordSet(`that which who whom whose why where when how if whether because`)
+
+// governs reports whether the clause's marker applies to the verb at i,
+// i.e. no modal or subordinate clause stands between them
+func (c timeClause) governs(i int) bool {
+	if c.at < 0 {
+		return true
+	}
+	for j, t := range c.tokens {
+		between := (j > i && t.Start < c.at) || (j < i && t.Start > c.at)
+		if between && (modals[t.Lower] || subordinators[t.Lower]) {
+			return false
+		}
+	}
+	return true
+}
+
+// markedClauses returns the clauses governed by a marker of the given
+// pattern. A clause with a marker but no verb ("Yesterday evening, ...")
+// passes the marker on to the next clause of the same sentence.
+func markedClauses(doc *document, marker *regexp.Regexp, conflicting ...*regexp.Regexp) []timeClause {
+	sentences := doc.segments(ScopeSentence)
+	clauses := doc.segments(ScopeClause)
+	result := make([]timeClause, 0)
+
+	carried, carriedSentence := "", -1
+	for _, clause := range clauses {
+		sentence := segmentIndex(sentences, clause.Start)
+		if sentence != carriedSentence {
+			carried = ""
+		}
+
+		if presentMarker.MatchString(clause.Text) || matchesAny(clause.Text, conflicting) {
+			carried = ""
+			continue
+		}
+
+		tokens := doc.tokensIn(clause)
+		locs := marker.FindAllStringIndex(clause.Text, -1)
+		if len(locs) == 0 {
+			if carried != "" {
+				result = append(result, timeClause{tokens: tokens, marker: carried, at: -1, before: true})
+				carried = ""
+			}
+			continue
+		}
+
+		markerText := strings.ToLower(clause.Text[locs[0][0]:locs[0][1]])
+		if !hasVerb(tokens) {
+			carried, carriedSentence = markerText, sentence
+			continue
+		}
+		carried = ""
+		result = append(result, timeClause{
+			tokens: tokens,
+			marker: markerText,
+			at:     clause.Start + locs[0][0],
+			before: strings.TrimSpace(clause.Text[:locs[0][0]]) == "",
+		})
+	}
+	return result
+}
+
+// segmentIndex returns the index of the segment containing offset
+func segmentIndex(segments []Segment, offset int) int {
+	for i, segment := range segments {
+		if offset >= segment.Start && offset < segment.End {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasVerb reports whether any token is a known verb form
+func hasVerb(tokens []token) bool {
+	for _, t := range tokens {
+		if _, _, ok := LookupVerb(t.Lower); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(text string, patterns []*regexp.Regexp) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// finiteVerbs returns the indexes of the verbs in tokens that follow a
+// subject, with the subject's person. Nouns that double as verbs are
+// skipped when another verb follows them: "my job interview was".
+func finiteVerbs(tokens []token) (indexes []int, persons []Person) {
+	for i, t := range tokens {
+		_, _, isVerb := LookupVerb(t.Lower)
+		_, negative := pastNegatives[t.Lower]
+		_, futureNegative := futureNegatives[t.Lower]
+		if !isVerb && !negative && !futureNegative {
+			continue
+		}
+		person, ok := subjectBefore(tokens, i)
+		if !ok {
+			continue
+		}
+		if i+1 < len(tokens) && isAuxiliary(tokens[i+1].Lower) && !isAuxiliary(t.Lower) {
+			continue
+		}
+		indexes = append(indexes, i)
+		persons = append(persons, person)
+	}
+	return indexes, persons
+}
+
+// isAuxiliary reports whether word is a form of be, have or do
+func isAuxiliary(word string) bool {
+	verb, _, ok := LookupVerb(word)
+	return ok && (verb == be || verb.Base == "have" || verb.Base == "do")
+}
+
+// isPerfectAuxiliary reports whether the verb at i is "have" helping a
+// participle, as in "I have finished" or "she has been"
+func isPerfectAuxiliary(tokens []token, i int) bool {
+	verb, _, _ := LookupVerb(tokens[i].Lower)
+	if verb == nil || verb.Base != "have" || i+1 >= len(tokens) {
+		return false
+	}
+	next := tokens[i+1].Lower
+	if adverbs[next] && i+2 < len(tokens) {
+		next = tokens[i+2].Lower
+	}
+	_, forms, ok := LookupVerb(next)
+	return ok && forms.Has(FormParticiple) && !forms.Has(FormBase)
+}
+
+// checkPastTense flags present-tense verbs in clauses with a past time
+// marker: "Yesterday I go" becomes "Yesterday I went"
+func checkPastTense(r *GrammarRule, doc *document) []Match {
+	matches := make([]Match, 0)
+	for _, clause := range markedClauses(doc, pastMarker, futureMarker) {
+		indexes, persons := finiteVerbs(clause.tokens)
+		for n, i := range indexes {
+			t := clause.tokens[i]
+			if !clause.governs(i) {
+				continue
+			}
+
+			if negative, ok := pastNegatives[t.Lower]; ok {
+				matches = append(matches, r.wordMatch(doc, t, t, negative,
+					fmt.Sprintf("Use past tense '%s' with '%s'", negative, clause.marker)))
+				continue
+			}
+
+			verb, forms, _ := LookupVerb(t.Lower)
+			if forms.Has(FormPast) || forms&(FormBase|FormThird) == 0 || isPerfectAuxiliary(clause.tokens, i) {
+				continue
+			}
+			past := verb.PastFor(persons[n] == Plural)
+			matches = append(matches, r.wordMatch(doc, t, t, past,
+				fmt.Sprintf("Use past tense '%s' with '%s'", past, clause.marker)))
+		}
+	}
+	return matches
+}
+
+// checkFutureTense flags past-tense verbs in clauses with a future time
+// marker: "Tomorrow I went" becomes "Tomorrow I will go"
+func checkFutureTense(r *GrammarRule, doc *document) []Match {
+	matches := make([]Match, 0)
+	for _, clause := range markedClauses(doc, futureMarker, pastMarker) {
+		indexes, _ := finiteVerbs(clause.tokens)
+		for _, i := range indexes {
+			t := clause.tokens[i]
+			if !clause.governs(i) {
+				continue
+			}
+
+			if negative, ok := futureNegatives[t.Lower]; ok {
+				matches = append(matches, r.wordMatch(doc, t, t, negative,
+					fmt.Sprintf("Use future tense '%s' with '%s'", negative, clause.marker)))
+				continue
+			}
+
+			verb, forms, _ := LookupVerb(t.Lower)
+			if !forms.Has(FormPast) || forms.Has(FormBase) {
+				continue
+			}
+
+			future := "will " + verb.Base
+			if verb.Base == "do" && i+1 < len(clause.tokens) {
+				// "did not go" and "did go" only need the auxiliary changed
+				if _, nextForms, ok := LookupVerb(clause.tokens[i+1].Lower); clause.tokens[i+1].Lower == "not" || (ok && nextForms.Has(FormBase)) {
+					future = "will"
+				}
+			}
+			if verb.Base == "have" && isPerfectAuxiliary(clause.tokens, i) {
+				continue
+			}
+			matches = append(matches, r.wordMatch(doc, t, t, future,
+				fmt.Sprintf("Use future tense '%s' with '%s'", future, clause.marker)))
+		}
+	}
+	return matches
+}
+
+// checkDurationTense flags simple tenses used with "for" or "since" for
+// something still going on: "I work here since 2019" becomes "I have been
+// working here since 2019", and "I know him for years" becomes "I have
+// known him for years"
+func checkDurationTense(r *GrammarRule, doc *document) []Match {
+	matches := make([]Match, 0)
+	for _, clause := range markedClauses(doc, durationMarker, pastMarker, futureMarker) {
+		indexes, persons := finiteVerbs(clause.tokens)
+		for n, i := range indexes {
+			t := clause.tokens[i]
+			verb, forms, ok := LookupVerb(t.Lower)
+			if !ok || !clause.governs(i) || forms&(FormBase|FormThird|FormPast) == 0 || isPerfectAuxiliary(clause.tokens, i) {
+				continue
+			}
+			if verb.Base == "do" || (i+1 < len(clause.tokens) && clause.tokens[i+1].Lower == "not") {
+				continue
+			}
+
+			// A simple past is only wrong when the duration leads the
+			// sentence and nothing marks the time as finished
+			if forms.Has(FormPast) && !forms.Has(FormBase) {
+				if !clause.before || statives[verb.Base] || sequenceWords.MatchString(doc.text) {
+					continue
+				}
+			}
+
+			perfect := haveFor(persons[n]) + " been " + verb.Ing
+			if statives[verb.Base] {
+				perfect = haveFor(persons[n]) + " " + verb.Participle
+			}
+			matches = append(matches, r.wordMatch(doc, t, t, perfect,
+				fmt.Sprintf("Use present perfect '%s' with '%s'", perfect, clause.marker)))
+		}
+	}
+	return matches
+}
@@ -0,0 +1,399 @@
+package rules
+
+import "strings"
+
+// Verb holds the principal forms of an English verb
+type Verb struct {
+	Base       string // go
+	Past       string // went
+	Participle string // gone
+	Third      string // goes
+	Ing        string // going
+}
+
+// Form is one of the forms of a verb; a word can be several at once, e.g.
+// "put" is base, past and participle
+type Form uint8
+
+const (
+	FormBase Form = 1 << iota
+	FormThird
+	FormPast
+	FormParticiple
+	FormIng
+)
+
+// Has reports whether f includes every form in other
+func (f Form) Has(other Form) bool {
+	return f&other == other
+}
+
+// irregularVerbs lists base, past and participle of the irregular verbs
+// learners use most. Alternatives such as "learnt" are left out; the
+// regular form is the one suggested.
+const irregularVerbs = `
+arise arose arisen
+awake awoke awoken
+bear bore born
+beat beat beaten
+become became become
+begin began begun
+bend bent bent
+bet bet bet
+bind bound bound
+bite bit bitten
+bleed bled bled
+blow blew blown
+break broke broken
+breed bred bred
+bring brought brought
+broadcast broadcast broadcast
+build built built
+burst burst burst
+buy bought bought
+catch caught caught
+choose chose chosen
+come came come
+cost cost cost
+creep crept crept
+cut cut cut
+deal dealt dealt
+dig dug dug
+do did done
+draw drew drawn
+drink drank drunk
+drive drove driven
+eat ate eaten
+fall fell fallen
+feed fed fed
+feel felt felt
+fight fought fought
+find found found
+flee fled fled
+fly flew flown
+forbid forbade forbidden
+forecast forecast forecast
+forget forgot forgotten
+forgive forgave forgiven
+freeze froze frozen
+get got got
+give gave given
+go went gone
+grow grew grown
+hang hung hung
+have had had
+hear heard heard
+hide hid hidden
+hit hit hit
+hold held held
+hurt hurt hurt
+keep kept kept
+kneel knelt knelt
+know knew known
+lay laid laid
+lead led led
+leave left left
+lend lent lent
+let let let
+lie lay lain
+light lit lit
+lose lost lost
+make made made
+mean meant meant
+meet met met
+mistake mistook mistaken
+overcome overcame overcome
+pay paid paid
+prove proved proven
+put put put
+quit quit quit
+read read read
+ride rode ridden
+ring rang rung
+rise rose risen
+run ran run
+say said said
+see saw seen
+seek sought sought
+sell sold sold
+send sent sent
+set set set
+shake shook shaken
+shine shone shone
+shoot shot shot
+show showed shown
+shrink shrank shrunk
+shut shut shut
+sing sang sung
+sink sank sunk
+sit sat sat
+sleep slept slept
+slide slid slid
+speak spoke spoken
+speed sped sped
+spend spent spent
+spin spun spun
+split split split
+spread spread spread
+stand stood stood
+steal stole stolen
+stick stuck stuck
+sting stung stung
+strike struck struck
+swear swore sworn
+sweep swept swept
+swim swam swum
+swing swung swung
+take took taken
+teach taught taught
+tear tore torn
+tell told told
+think thought thought
+throw threw thrown
+understand understood understood
+undertake undertook undertaken
+upset upset upset
+wake woke woken
+wear wore worn
+win won won
+withdraw withdrew withdrawn
+write wrote written
+`
+
+// regularVerbs lists common verbs with regular -ed forms
+const regularVerbs = `
+accept achieve add admire admit advise affect agree allow announce answer
+apologise apologize appear apply appreciate approve argue arrange arrive ask
+attach attack attend avoid bake believe belong borrow bother call calm cancel
+care carry change chase check cheer clean clear climb close collect combine
+commit communicate compare compete complain complete concentrate confirm
+connect consider contain continue contribute control convert convince cook
+copy correct count cover crash create cross cry dance decide declare decrease
+deliver depend describe deserve design destroy develop die disagree discover
+discuss dislike divide double doubt drop earn employ encourage end enjoy enter
+establish estimate examine expect explain explore express fail fill finish fit
+fix follow force gain gather grab guess handle happen hate help hope hug hurry
+identify ignore imagine impress improve include increase inform inspire
+install intend interview introduce invest invite join joke jump kick kill kiss
+knock laugh launch learn like limit listen live load lock look love manage
+marry match measure mention migrate mind miss mix move need notice obtain
+occur offer open organise organize own pack paint pass perform permit pick
+plan plant play please practice practise prefer prepare present prevent print
+produce promise protect provide publish pull punish push qualify raise reach
+realise realize receive recognise recognize recommend recover reduce refer
+refuse regret reject relax release rely remain remember remind remove rent
+repair repeat replace reply report request require rescue resign respect
+retire return review rush sail save scream search select serve settle share
+shout sign smile solve start stay stop study submit succeed suffer suggest
+supply support suppose surprise survive switch talk taste test thank touch
+train transfer travel treat trust try turn use visit vote wait walk want warn
+wash waste watch wish wonder work worry
+`
+
+// doublingVerbs double their final consonant before -ed and -ing even though
+// they have more than one syllable, e.g. "preferred"
+var doublingVerbs = map[string]bool{
+	"admit": true, "begin": true, "commit": true, "control": true, "forget": true,
+	"occur": true, "permit": true, "prefer": true, "quit": true, "refer": true,
+	"regret": true, "submit": true, "transfer": true, "upset": true,
+}
+
+// verbs maps every base form to its verb, and lexicon maps every surface
+// form to the verb and the forms it stands for. They are built during
+// variable initialisation so the rule files loaded in init can use them.
+var verbs, lexicon = buildLexicon()
+
+type lexiconEntry struct {
+	verb  *Verb
+	forms Form
+}
+
+// be is kept apart because it has more forms than the table holds
+var be = &Verb{Base: "be", Past: "was", Participle: "been", Third: "is", Ing: "being"}
+
+func buildLexicon() (map[string]*Verb, map[string]lexiconEntry) {
+	verbs := make(map[string]*Verb)
+	lexicon := make(map[string]lexiconEntry)
+
+	for _, line := range strings.Split(strings.TrimSpace(irregularVerbs), "\n") {
+		parts := strings.Fields(line)
+		base := parts[0]
+		addVerb(verbs, lexicon, &Verb{
+			Base:       base,
+			Past:       parts[1],
+			Participle: parts[2],
+			Third:      thirdPerson(base),
+			Ing:        ingForm(base),
+		})
+	}
+	for _, base := range strings.Fields(regularVerbs) {
+		past := regularPast(base)
+		addVerb(verbs, lexicon, &Verb{
+			Base:       base,
+			Past:       past,
+			Participle: past,
+			Third:      thirdPerson(base),
+			Ing:        ingForm(base),
+		})
+	}
+
+	verbs[be.Base] = be
+	for word, forms := range map[string]Form{
+		"be": FormBase, "am": FormBase, "are": FormBase, "is": FormThird,
+		"was": FormPast, "were": FormPast, "been": FormParticiple, "being": FormIng,
+	} {
+		lexicon[word] = lexiconEntry{verb: be, forms: forms}
+	}
+
+	return verbs, lexicon
+}
+
+// addVerb registers v; the first verb registered for a surface form wins,
+// so irregular verbs take precedence, e.g. "found" stays the past of "find"
+func addVerb(verbs map[string]*Verb, lexicon map[string]lexiconEntry, v *Verb) {
+	if _, exists := verbs[v.Base]; exists {
+		return
+	}
+	verbs[v.Base] = v
+
+	for _, form := range []struct {
+		word string
+		form Form
+	}{
+		{v.Base, FormBase}, {v.Third, FormThird}, {v.Past, FormPast},
+		{v.Participle, FormParticiple}, {v.Ing, FormIng},
+	} {
+		entry, exists := lexicon[form.word]
+		if exists && entry.verb != v {
+			continue
+		}
+		lexicon[form.word] = lexiconEntry{verb: v, forms: entry.forms | form.form}
+	}
+}
+
+// LookupVerb returns the verb a word is a form of and which forms it is.
+// The word is matched case-insensitively; ok is false for unknown words.
+func LookupVerb(word string) (verb *Verb, forms Form, ok bool) {
+	entry, ok := lexicon[strings.ToLower(word)]
+	return entry.verb, entry.forms, ok
+}
+
+// Conjugate returns the forms of the verb with the given base form
+func Conjugate(base string) (*Verb, bool) {
+	verb, ok := verbs[strings.ToLower(base)]
+	return verb, ok
+}
+
+// PastFor returns the simple past agreeing with the subject; only "be"
+// distinguishes "was" from "were"
+func (v *Verb) PastFor(plural bool) string {
+	if v == be && plural {
+		return "were"
+	}
+	return v.Past
+}
+
+// PresentFor returns the simple present agreeing with the subject
+func (v *Verb) PresentFor(person Person) string {
+	if v == be {
+		switch person {
+		case FirstSingular:
+			return "am"
+		case ThirdSingular:
+			return "is"
+		default:
+			return "are"
+		}
+	}
+	if person == ThirdSingular {
+		return v.Third
+	}
+	return v.Base
+}
+
+// thirdPerson builds the -s form: goes, watches, tries, plays
+func thirdPerson(base string) string {
+	switch {
+	case base == "have":
+		return "has"
+	case base == "be":
+		return "is"
+	case strings.HasSuffix(base, "y") && !endsWithVowel(base[:len(base)-1]):
+		return base[:len(base)-1] + "ies"
+	case strings.HasSuffix(base, "o"), strings.HasSuffix(base, "s"), strings.HasSuffix(base, "x"),
+		strings.HasSuffix(base, "z"), strings.HasSuffix(base, "ch"), strings.HasSuffix(base, "sh"):
+		return base + "es"
+	default:
+		return base + "s"
+	}
+}
+
+// regularPast builds the -ed form: worked, lived, tried, stopped
+func regularPast(base string) string {
+	switch {
+	case strings.HasSuffix(base, "e"):
+		return base + "d"
+	case strings.HasSuffix(base, "y") && !endsWithVowel(base[:len(base)-1]):
+		return base[:len(base)-1] + "ied"
+	case doublesFinal(base):
+		return base + base[len(base)-1:] + "ed"
+	default:
+		return base + "ed"
+	}
+}
+
+// ingForm builds the -ing form: going, making, lying, stopping, seeing
+func ingForm(base string) string {
+	switch {
+	case strings.HasSuffix(base, "ie"):
+		return base[:len(base)-2] + "ying"
+	case strings.HasSuffix(base, "e") && !strings.HasSuffix(base, "ee") && !strings.HasSuffix(base, "ye") &&
+		!strings.HasSuffix(base, "oe") && len(base) > 2:
+		return base[:len(base)-1] + "ing"
+	case doublesFinal(base):
+		return base + base[len(base)-1:] + "ing"
+	default:
+		return base + "ing"
+	}
+}
+
+// doublesFinal reports whether the final consonant doubles before a vowel
+// suffix: one-syllable consonant-vowel-consonant verbs like "stop", and the
+// stressed-final verbs in doublingVerbs
+func doublesFinal(base string) bool {
+	if doublingVerbs[base] {
+		return true
+	}
+	n := len(base)
+	if n < 3 || syllables(base) != 1 {
+		return false
+	}
+	last, vowel, before := base[n-1], base[n-2], base[n-3]
+	return !isVowel(last) && !strings.ContainsRune("wxy", rune(last)) &&
+		isVowel(vowel) && !isVowel(before)
+}
+
+// syllables counts vowel groups, which is close enough for short verbs
+func syllables(word string) int {
+	count := 0
+	inVowel := false
+	for i := 0; i < len(word); i++ {
+		v := isVowel(word[i])
+		if v && !inVowel {
+			count++
+		}
+		inVowel = v
+	}
+	if strings.HasSuffix(word, "e") && count > 1 {
+		count--
+	}
+	return count
+}
+
+func isVowel(b byte) bool {
+	return strings.IndexByte("aeiou", b) >= 0
+}
+
+func endsWithVowel(s string) bool {
+	return s != "" && isVowel(s[len(s)-1])
+}
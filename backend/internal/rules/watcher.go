@@ -0,0 +1,92 @@
+package rules
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Watcher polls a rule directory and reloads the active rule set when any
+// rule file changes. A directory that fails to load is logged and ignored,
+// so the running rule set stays in place until the files are fixed.
+type Watcher struct {
+	dir         string
+	interval    time.Duration
+	fingerprint string
+	stop        chan struct{}
+}
+
+// NewWatcher creates a watcher for dir. Call Run to start polling.
+func NewWatcher(dir string, interval time.Duration) *Watcher {
+	fingerprint, _ := dirFingerprint(dir)
+	return &Watcher{
+		dir:         dir,
+		interval:    interval,
+		fingerprint: fingerprint,
+		stop:        make(chan struct{}),
+	}
+}
+
+// Run polls the directory until Stop is called
+func (w *Watcher) Run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.check()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// Stop ends the polling loop
+func (w *Watcher) Stop() {
+	close(w.stop)
+}
+
+// check reloads the rules if the directory changed since the last check
+func (w *Watcher) check() {
+	fingerprint, err := dirFingerprint(w.dir)
+	if err != nil {
+		log.Printf("Rule watcher: cannot read %s: %v", w.dir, err)
+		return
+	}
+	if fingerprint == w.fingerprint {
+		return
+	}
+	w.fingerprint = fingerprint
+
+	rs, err := LoadDir(w.dir)
+	if err != nil {
+		log.Printf("Rule reload rejected, keeping %d active rules: %v", len(Active().Rules), err)
+		return
+	}
+
+	SetActive(rs)
+	log.Printf("Reloaded %d grammar rules from %s", len(rs.Rules), w.dir)
+}
+
+// dirFingerprint summarises the names, sizes and modification times of the
+// rule files in dir so changes can be spotted without reading them
+func dirFingerprint(dir string) (string, error) {
+	names, err := ruleFileNames(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, name := range names {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", name, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
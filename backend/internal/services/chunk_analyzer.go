@@ -1,8 +1,10 @@
 package services
 
 import (
+	"context"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/rules"
 )
@@ -10,6 +12,9 @@ import (
 // ChunkAnalyzer analyzes streaming speech chunks for grammar errors in real-time
 type ChunkAnalyzer struct {
 	grammarDetector *GrammarDetector
+	fluencyAnalyzer *FluencyAnalyzer // Tracks the same sessions for fluency, may be nil
+	pronunciation   *PronunciationAnalyzer // Tracks the same sessions for pronunciation, may be nil
+	lexicalAnalyzer *LexicalAnalyzer       // Finds overused basic words, may be nil
 	sessions        map[string]*AnalysisSession
 	mu              sync.RWMutex
 }
@@ -21,6 +26,13 @@ type AnalysisSession struct {
 	detectedErrors  map[string]bool // Track which errors we've already flagged
 	fullTranscript  string
 	nativeLanguage  string
+	profile         string // Rule profile chosen for the session
+	style           string // Explanation style chosen for the session
+	vocabulary      *vocabularyUsage // Basic words said and the tips sent for them
+	speech          *speechUsage     // Final transcripts with their timings, for the speech metrics
+	findings        map[string]*scoredFinding // Errors shown to the user, for the grammar score
+	shown           map[string]bool           // Findings already counted, by utterance ID and finding key
+	expectedFixes   []expectedFix             // Errors the next utterance should repeat correctly
 	mu              sync.Mutex
 }
 
@@ -28,20 +40,33 @@ type AnalysisSession struct {
 type ChunkError struct {
 	ChunkText      string  `json:"chunk_text"`
 	ErrorResult    *ErrorResult `json:"error"`
+	Errors         []*ErrorResult `json:"errors"` // Every new error in ChunkText, ErrorResult is the most severe
 	WordPosition   int     `json:"word_position"`
 	IsNewError     bool    `json:"is_new_error"`
 }
 
-// NewChunkAnalyzer creates a new chunk analyzer
-func NewChunkAnalyzer(grammarDetector *GrammarDetector) *ChunkAnalyzer {
+// NewChunkAnalyzer creates a new chunk analyzer. Sessions started or
+// ended on it are also started or ended on fluencyAnalyzer and pronunciation.
+func NewChunkAnalyzer(grammarDetector *GrammarDetector, fluencyAnalyzer *FluencyAnalyzer, pronunciation *PronunciationAnalyzer, lexicalAnalyzer *LexicalAnalyzer) *ChunkAnalyzer {
 	return &ChunkAnalyzer{
 		grammarDetector: grammarDetector,
+		fluencyAnalyzer: fluencyAnalyzer,
+		pronunciation:   pronunciation,
+		lexicalAnalyzer: lexicalAnalyzer,
 		sessions:        make(map[string]*AnalysisSession),
 	}
 }
 
-// StartSession initializes a new analysis session
-func (ca *ChunkAnalyzer) StartSession(sessionID, nativeLanguage string) {
+// StartSession initializes a new analysis session. profile selects the
+// rule profile and style the explanation style, empty for the defaults.
+func (ca *ChunkAnalyzer) StartSession(sessionID, nativeLanguage, profile, style string) {
+	if ca.fluencyAnalyzer != nil {
+		ca.fluencyAnalyzer.StartSession(sessionID)
+	}
+	if ca.pronunciation != nil {
+		ca.pronunciation.StartSession(sessionID)
+	}
+
 	ca.mu.Lock()
 	defer ca.mu.Unlock()
 
@@ -51,22 +76,22 @@ func (ca *ChunkAnalyzer) StartSession(sessionID, nativeLanguage string) {
 		detectedErrors: make(map[string]bool),
 		fullTranscript: "",
 		nativeLanguage: nativeLanguage,
+		profile:        profile,
+		style:          style,
+		vocabulary:     newVocabularyUsage(),
+		speech:         newSpeechUsage(),
+		findings:       make(map[string]*scoredFinding),
+		shown:          make(map[string]bool),
 	}
 }
 
-// AnalyzeChunk processes an interim or final transcript chunk
-func (ca *ChunkAnalyzer) AnalyzeChunk(sessionID, chunkText string, isFinal bool) (*ChunkError, error) {
-	ca.mu.RLock()
-	session, exists := ca.sessions[sessionID]
-	ca.mu.RUnlock()
-
-	if !exists {
-		// Auto-create session if it doesn't exist
-		ca.StartSession(sessionID, "Hindi")
-		ca.mu.RLock()
-		session = ca.sessions[sessionID]
-		ca.mu.RUnlock()
-	}
+// AnalyzeChunk processes an interim or final transcript chunk. The LLM gets
+// DefaultLLMBudget to answer, shared by the chunk and window checks; errors
+// it finds later are passed to onLate, from another goroutine, if onLate is
+// not nil.
+func (ca *ChunkAnalyzer) AnalyzeChunk(ctx context.Context, sessionID, chunkText string, isFinal bool, onLate func(*ChunkError)) (*ChunkError, error) {
+	session := ca.session(sessionID)
+	deadline := time.Now().Add(DefaultLLMBudget)
 
 	session.mu.Lock()
 	defer session.mu.Unlock()
@@ -96,54 +121,224 @@ func (ca *ChunkAnalyzer) AnalyzeChunk(sessionID, chunkText string, isFinal bool)
 	// Also analyze the new chunk itself
 	if len(words) >= 2 {
 		// Check the new chunk for errors
-		errorResult, err := ca.grammarDetector.DetectGrammarError(chunkText, session.nativeLanguage)
-		if err != nil {
-			return nil, err
+		chunkError, err := ca.detectNewErrors(ctx, session, chunkText, len(session.slidingWindow)-len(words), DefaultLLMBudget, onLate)
+		if err != nil || chunkError != nil {
+			return chunkError, err
 		}
+	}
 
-		if errorResult != nil {
-			// Create a unique key for this error to avoid double-flagging
-			errorKey := errorResult.RuleID + ":" + errorResult.Original
-			
-			// Check if we've already flagged this exact error
-			if !session.detectedErrors[errorKey] {
-				session.detectedErrors[errorKey] = true
-				
-				return &ChunkError{
-					ChunkText:    chunkText,
-					ErrorResult:  errorResult,
-					WordPosition: len(session.slidingWindow) - len(words),
-					IsNewError:   true,
-				}, nil
+	// Also check the sliding window context for errors, with what is left of
+	// the budget so a clean chunk doesn't make the caller wait twice
+	if len(session.slidingWindow) >= 3 {
+		start := max(len(session.slidingWindow)-5, 0)
+		windowText := strings.Join(session.slidingWindow[start:], " ")
+		return ca.detectNewErrors(ctx, session, windowText, start, max(time.Until(deadline), time.Millisecond), onLate)
+	}
+
+	return nil, nil
+}
+
+// session returns the session, creating it with the defaults if needed
+func (ca *ChunkAnalyzer) session(sessionID string) *AnalysisSession {
+	ca.mu.RLock()
+	session, exists := ca.sessions[sessionID]
+	ca.mu.RUnlock()
+
+	if !exists {
+		// Auto-create session if it doesn't exist
+		ca.StartSession(sessionID, "Hindi", "", "")
+		ca.mu.RLock()
+		session = ca.sessions[sessionID]
+		ca.mu.RUnlock()
+	}
+	return session
+}
+
+// TrackVocabulary counts the overused basic words in a final transcript
+// and returns a tip for each word that has just been said too often. Each
+// word gets at most one tip per session.
+func (ca *ChunkAnalyzer) TrackVocabulary(sessionID, text string) []VocabularyTip {
+	if ca.lexicalAnalyzer == nil {
+		return nil
+	}
+	session := ca.session(sessionID)
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	return ca.lexicalAnalyzer.track(session.vocabulary, text)
+}
+
+// RecordUtterance adds the final transcript of an utterance to the session's
+// speech metrics and returns them. words are the STT's word timings, nil if
+// it sent none.
+func (ca *ChunkAnalyzer) RecordUtterance(sessionID, utteranceID, text string, words []TranscriptWord) SpeechMetrics {
+	session := ca.session(sessionID)
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	// An utterance after an interruption may repeat the corrected sentence.
+	// Interruptions on an interim transcript come before its final one,
+	// which is the error itself and not the repeat.
+	if len(session.expectedFixes) > 0 {
+		heard := correctionWords(text)
+		pending := session.expectedFixes[:0]
+		for _, expected := range session.expectedFixes {
+			if expected.utteranceID == utteranceID {
+				pending = append(pending, expected)
+				continue
+			}
+			if finding, ok := session.findings[expected.key]; ok && expected.fixedBy(heard) {
+				finding.fixed++
 			}
 		}
+		session.expectedFixes = pending
 	}
 
-	// Also check the sliding window context for errors
-	if len(session.slidingWindow) >= 3 {
-		windowText := strings.Join(session.slidingWindow[len(session.slidingWindow)-5:], " ")
-		errorResult, err := ca.grammarDetector.DetectGrammarError(windowText, session.nativeLanguage)
-		if err != nil {
-			return nil, err
+	session.speech.record(text, words, time.Now())
+	return session.speech.metrics()
+}
+
+// RecordErrors counts the errors shown to the user in an utterance towards
+// the grammar score. Errors shown again for the same utterance, as late
+// corrections repeat the rule findings, count once. Late corrections for a
+// session that has ended are dropped.
+func (ca *ChunkAnalyzer) RecordErrors(sessionID, utteranceID string, errorResults []*ErrorResult) {
+	ca.mu.RLock()
+	session, exists := ca.sessions[sessionID]
+	ca.mu.RUnlock()
+
+	if !exists {
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	for _, errorResult := range errorResults {
+		key := findingKey(errorResult)
+		if session.shown[utteranceID+"|"+key] {
+			continue
 		}
+		session.shown[utteranceID+"|"+key] = true
 
-		if errorResult != nil {
-			errorKey := errorResult.RuleID + ":" + errorResult.Original
-			
-			if !session.detectedErrors[errorKey] {
-				session.detectedErrors[errorKey] = true
-				
-				return &ChunkError{
-					ChunkText:    windowText,
-					ErrorResult:  errorResult,
-					WordPosition: len(session.slidingWindow) - 5,
-					IsNewError:   true,
-				}, nil
+		finding, ok := session.findings[key]
+		if !ok {
+			finding = &scoredFinding{severity: rules.Severity(errorResult.Severity), category: errorResult.Category}
+			session.findings[key] = finding
+		}
+		finding.occurrences++
+	}
+}
+
+// ExpectFixes tells the analyzer the user was asked to repeat the corrected
+// sentence of errorResults, found in an utterance. The errors the final
+// transcript of the next utterance says correctly count less towards the
+// grammar score.
+func (ca *ChunkAnalyzer) ExpectFixes(sessionID, utteranceID string, errorResults []*ErrorResult) {
+	session := ca.session(sessionID)
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	session.expectedFixes = make([]expectedFix, 0, len(errorResults))
+	for _, errorResult := range errorResults {
+		session.expectedFixes = append(session.expectedFixes, newExpectedFix(utteranceID, errorResult))
+	}
+}
+
+// GetGrammarScore scores the grammar of a session so far, or returns nil if
+// there is no such session or nothing was said in it
+func (ca *ChunkAnalyzer) GetGrammarScore(sessionID string) *GrammarScore {
+	ca.mu.RLock()
+	session, exists := ca.sessions[sessionID]
+	ca.mu.RUnlock()
+
+	if !exists {
+		return nil
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	return scoreGrammar(session.findings, session.speech.metrics().WordCount)
+}
+
+// GetSpeechMetrics returns the speech metrics of a session so far, or nil
+// if there is no such session
+func (ca *ChunkAnalyzer) GetSpeechMetrics(sessionID string) *SpeechMetrics {
+	ca.mu.RLock()
+	session, exists := ca.sessions[sessionID]
+	ca.mu.RUnlock()
+
+	if !exists {
+		return nil
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	metrics := session.speech.metrics()
+	return &metrics
+}
+
+// detectNewErrors runs grammar detection on text, waiting up to budget for
+// the LLM, and returns the errors that have not been flagged before in this
+// session. Caller must hold session.mu.
+func (ca *ChunkAnalyzer) detectNewErrors(ctx context.Context, session *AnalysisSession, text string, wordPosition int, budget time.Duration, onLate func(*ChunkError)) (*ChunkError, error) {
+	opts := DetectOptions{
+		NativeLanguage: session.nativeLanguage,
+		Profile:        session.profile,
+		Style:          session.style,
+		Budget:         budget,
+	}
+	if onLate != nil {
+		opts.OnLate = func(results []*ErrorResult) {
+			session.mu.Lock()
+			chunkError := session.newErrors(text, wordPosition, results)
+			session.mu.Unlock()
+
+			if chunkError != nil {
+				onLate(chunkError)
 			}
 		}
 	}
 
-	return nil, nil
+	errorResults, err := ca.grammarDetector.DetectGrammarErrors(ctx, text, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return session.newErrors(text, wordPosition, errorResults), nil
+}
+
+// newErrors marks errorResults as detected and returns the ones that were
+// not flagged before, nil if there are none. Caller must hold s.mu.
+func (s *AnalysisSession) newErrors(text string, wordPosition int, errorResults []*ErrorResult) *ChunkError {
+	newErrors := make([]*ErrorResult, 0, len(errorResults))
+	for _, errorResult := range errorResults {
+		// Create a unique key for this error to avoid double-flagging
+		errorKey := findingKey(errorResult)
+
+		// Check if we've already flagged this exact error
+		if !s.detectedErrors[errorKey] {
+			s.detectedErrors[errorKey] = true
+			newErrors = append(newErrors, errorResult)
+		}
+	}
+
+	if len(newErrors) == 0 {
+		return nil
+	}
+
+	return &ChunkError{
+		ChunkText:    text,
+		ErrorResult:  MostSevere(newErrors),
+		Errors:       newErrors,
+		WordPosition: wordPosition,
+		IsNewError:   true,
+	}
 }
 
 // GetFullTranscript returns the complete transcript for a session
@@ -196,6 +391,13 @@ func (ca *ChunkAnalyzer) ResetErrorTracking(sessionID string) {
 
 // EndSession removes a session from memory
 func (ca *ChunkAnalyzer) EndSession(sessionID string) {
+	if ca.fluencyAnalyzer != nil {
+		ca.fluencyAnalyzer.EndSession(sessionID)
+	}
+	if ca.pronunciation != nil {
+		ca.pronunciation.EndSession(sessionID)
+	}
+
 	ca.mu.Lock()
 	defer ca.mu.Unlock()
 	
@@ -204,7 +406,7 @@ func (ca *ChunkAnalyzer) EndSession(sessionID string) {
 
 // AnalyzeInterimText is optimized for analyzing interim (non-final) transcripts
 // It focuses on the most recent words to minimize false positives
-func (ca *ChunkAnalyzer) AnalyzeInterimText(sessionID, interimText string) ([]*ChunkError, error) {
+func (ca *ChunkAnalyzer) AnalyzeInterimText(ctx context.Context, sessionID, interimText string) ([]*ChunkError, error) {
 	ca.mu.RLock()
 	session, exists := ca.sessions[sessionID]
 	ca.mu.RUnlock()
@@ -213,9 +415,6 @@ func (ca *ChunkAnalyzer) AnalyzeInterimText(sessionID, interimText string) ([]*C
 		return nil, nil
 	}
 
-	session.mu.Lock()
-	defer session.mu.Unlock()
-
 	// For interim text, only check for obvious patterns
 	// Extract last few words that form a complete phrase
 	words := strings.Fields(interimText)
@@ -223,17 +422,25 @@ func (ca *ChunkAnalyzer) AnalyzeInterimText(sessionID, interimText string) ([]*C
 		return nil, nil
 	}
 
+	session.mu.Lock()
+	nativeLanguage, style := session.nativeLanguage, session.style
+
 	errors := make([]*ChunkError, 0)
+	found := make([]*ErrorResult, 0)
+	reported := make(map[string]bool) // The same match shows up in several overlapping phrases
 
 	// Check common patterns that appear in real-time speech
 	// Focus on subject-verb agreement, tense, and common errors
 	commonPatterns := []struct {
 		pattern string
-		check   func(string) (*rules.GrammarRule, string)
+		check   func(string) []rules.Match
 	}{
-		{"subject-verb", func(text string) (*rules.GrammarRule, string) {
+		{"subject-verb", func(text string) []rules.Match {
 			// Check for "I has", "he have", etc.
-			return rules.DetectError(text)
+			return rules.DetectAllWith(text, rules.Options{
+				Profile:        session.profile,
+				NativeLanguage: session.nativeLanguage,
+			})
 		}},
 	}
 
@@ -249,33 +456,55 @@ func (ca *ChunkAnalyzer) AnalyzeInterimText(sessionID, interimText string) ([]*C
 				continue
 			}
 
-			rule, corrected := pattern.check(phrase)
-			if rule != nil {
-				errorKey := rule.ID + ":" + phrase
-				if !session.detectedErrors[errorKey] {
-					// Don't mark as detected yet for interim results
-					// Only mark when final
-					
-					errorResult := &ErrorResult{
-						Original:           phrase,
-						Corrected:          corrected,
-						ErrorType:          rule.ErrorType,
-						ExplanationEnglish: rule.Description,
-						ExplanationNative:  ca.grammarDetector.generateNativeExplanation(rule.Description, session.nativeLanguage),
-						RuleID:             rule.ID,
-						Confidence:         0.9, // Slightly lower for interim
-					}
-
-					errors = append(errors, &ChunkError{
-						ChunkText:    phrase,
-						ErrorResult:  errorResult,
-						WordPosition: i,
-						IsNewError:   true,
-					})
+			for _, m := range pattern.check(phrase) {
+				errorKey := m.Rule.ID + ":" + m.Text
+				if session.detectedErrors[errorKey] || reported[errorKey] {
+					continue
 				}
+				// Don't mark as detected yet for interim results
+				// Only mark when final
+				reported[errorKey] = true
+
+				errorResult := &ErrorResult{
+					Original:           phrase,
+					Corrected:          rules.ApplyMatches(phrase, []rules.Match{m}),
+					ErrorType:          m.Rule.ErrorType,
+					ExplanationEnglish: m.Explanation(),
+					RuleID:             m.Rule.ID,
+					Confidence:         min(m.Rule.Confidence, 0.9), // Slightly lower for interim
+					Source:             SourceRules,
+					Category:           m.Rule.Category,
+					Severity:           string(m.Rule.Severity),
+					Start:              m.Start,
+					End:                m.End,
+					WordStart:          m.WordStart,
+					WordEnd:            m.WordEnd,
+					Matched:            m.Text,
+					Replacement:        m.Replacement,
+					Edits:              m.Edits,
+					rule:               m.Rule,
+				}
+				found = append(found, errorResult)
+
+				errors = append(errors, &ChunkError{
+					ChunkText:    phrase,
+					ErrorResult:  errorResult,
+					Errors:       []*ErrorResult{errorResult},
+					WordPosition: i + m.WordStart,
+					IsNewError:   true,
+				})
 			}
 		}
 	}
+	session.mu.Unlock()
+
+	// Explanations may be translated by the LLM, so they run unlocked and
+	// within the live budget
+	if len(found) > 0 {
+		budgetCtx, cancel := context.WithTimeout(ctx, DefaultLLMBudget)
+		defer cancel()
+		ca.grammarDetector.explainResults(budgetCtx, found, nativeLanguage, style)
+	}
 
 	return errors, nil
 }
@@ -304,14 +533,38 @@ func (ca *ChunkAnalyzer) GetSessionStats(sessionID string) map[string]interface{
 		errorRate = float64(len(session.detectedErrors)) / float64(wordCount) * 100
 	}
 
-	return map[string]interface{}{
+	stats := map[string]interface{}{
 		"session_id":      session.sessionID,
 		"word_count":      wordCount,
 		"error_count":     len(session.detectedErrors),
 		"error_rate":      errorRate,
 		"full_transcript": session.fullTranscript,
 		"errors_detected": ca.getErrorsList(session),
+		"speech_metrics":  session.speech.metrics(),
+	}
+
+	// The error rate above counts every error alike; the score weighs them
+	if score := scoreGrammar(session.findings, session.speech.metrics().WordCount); score != nil {
+		stats["grammar_score"] = score.Score
+		stats["grammar_breakdown"] = score.Breakdown
+	}
+
+	// Fluency is reported next to grammar, never counted as errors
+	if ca.fluencyAnalyzer != nil {
+		if fluency := ca.fluencyAnalyzer.GetMetrics(sessionID); fluency != nil {
+			stats["fluency"] = fluency
+		}
 	}
+	if ca.lexicalAnalyzer != nil {
+		stats["vocabulary"] = session.vocabulary.summary()
+	}
+	if ca.pronunciation != nil {
+		if words := ca.pronunciation.WordsToPractise(sessionID); words != nil {
+			stats["words_to_practise"] = words
+		}
+	}
+
+	return stats
 }
 
 // getErrorsList returns a list of all errors detected in a session
@@ -6,13 +6,21 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
+
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/translit"
 )
 
+// defaultTTSVoice reads English and other Latin-script text
+const defaultTTSVoice = "aura-asteria-en"
+
 // DeepgramService handles STT and TTS with Deepgram
 type DeepgramService struct {
 	apiKey     string
 	httpClient *http.Client
+	voice      string // Voice for Latin-script text, DEEPGRAM_TTS_VOICE
 }
 
 // TranscriptResult represents STT result
@@ -20,13 +28,29 @@ type TranscriptResult struct {
 	Text       string  `json:"text"`
 	Confidence float64 `json:"confidence"`
 	IsFinal    bool    `json:"is_final"`
+	Words      []TranscriptWord `json:"words"`
+}
+
+// TranscriptWord is one word of a transcript as Deepgram returns it
+type TranscriptWord struct {
+	Word           string  `json:"word"`
+	PunctuatedWord string  `json:"punctuated_word,omitempty"`
+	Start          float64 `json:"start"` // Seconds from the start of the audio
+	End            float64 `json:"end"`
+	Confidence     float64 `json:"confidence"`
 }
 
 // NewDeepgramService creates a new Deepgram service
 func NewDeepgramService() *DeepgramService {
+	voice := os.Getenv("DEEPGRAM_TTS_VOICE")
+	if voice == "" {
+		voice = defaultTTSVoice
+	}
+
 	return &DeepgramService{
 		apiKey: os.Getenv("DEEPGRAM_API_KEY"),
 		httpClient: &http.Client{},
+		voice:      voice,
 	}
 }
 
@@ -59,6 +83,7 @@ func (ds *DeepgramService) TranscribeAudio(audioData []byte) (*TranscriptResult,
 				Alternatives []struct {
 					Transcript string  `json:"transcript"`
 					Confidence float64 `json:"confidence"`
+					Words      []TranscriptWord `json:"words"`
 				} `json:"alternatives"`
 			} `json:"channels"`
 		} `json:"results"`
@@ -77,6 +102,7 @@ func (ds *DeepgramService) TranscribeAudio(audioData []byte) (*TranscriptResult,
 			Text:       alt.Transcript,
 			Confidence: alt.Confidence,
 			IsFinal:    response.Metadata.IsFinal,
+			Words:      alt.Words,
 		}, nil
 	}
 
@@ -85,7 +111,41 @@ func (ds *DeepgramService) TranscribeAudio(audioData []byte) (*TranscriptResult,
 
 // TextToSpeech converts text to speech using Deepgram
 func (ds *DeepgramService) TextToSpeech(text string) ([]byte, error) {
-	url := "https://api.deepgram.com/v1/speak?model=aura-asteria-en"
+	return ds.TextToSpeechVoice(text, ds.voice)
+}
+
+// ExplanationSpeech picks what to say for a finding's explanation and the
+// voice to say it with. Latin-script explanations, i.e. romanized,
+// code-mixed or English ones, are read by the English voice. Native script
+// in the native style needs a voice for the language, set with
+// DEEPGRAM_TTS_VOICE_<LANGUAGE>, e.g. DEEPGRAM_TTS_VOICE_HINDI; without one
+// the explanation is read transliterated, or in English if its script has
+// no transliteration. A terse explanation is only the English correction.
+func (ds *DeepgramService) ExplanationSpeech(result *ErrorResult, language, style string) (text, voice string) {
+	if result.Depth == DepthTerse && result.Correction != "" {
+		return result.Correction, ds.voice
+	}
+
+	text = result.ExplanationNative
+	if translit.IsLatin(text) {
+		return text, ds.voice
+	}
+
+	if style == StyleNative || style == "" {
+		key := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(language), " ", "_"))
+		if voice := os.Getenv("DEEPGRAM_TTS_VOICE_" + key); voice != "" {
+			return text, voice
+		}
+	}
+	if romanized, ok := romanize(text); ok {
+		return romanized, ds.voice
+	}
+	return result.ExplanationEnglish, ds.voice
+}
+
+// TextToSpeechVoice is TextToSpeech with a Deepgram voice model
+func (ds *DeepgramService) TextToSpeechVoice(text, voice string) ([]byte, error) {
+	endpoint := "https://api.deepgram.com/v1/speak?model=" + url.QueryEscape(voice)
 
 	requestBody := map[string]string{
 		"text": text,
@@ -96,7 +156,7 @@ func (ds *DeepgramService) TextToSpeech(text string) ([]byte, error) {
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/rules"
+)
+
+// Explanation depths: how much of a finding is explained
+const (
+	DepthTerse  = "terse"  // The correction only
+	DepthRule   = "rule"   // The correction and the one-line rule
+	DepthLesson = "lesson" // The rule, the grammar behind it and two example sentences
+)
+
+// DepthAuto picks the depth of each finding from the persona's strictness
+// and how often the user has been told about the rule
+const DepthAuto = "auto"
+
+// ExplanationDepths lists the explanation depths, shallowest first
+var ExplanationDepths = []string{DepthTerse, DepthRule, DepthLesson}
+
+// depthAliases are the other names users give the depths
+var depthAliases = map[string]string{
+	"short":      DepthTerse,
+	"correction": DepthTerse,
+	"brief":      DepthRule,
+	"normal":     DepthRule,
+	"detailed":   DepthLesson,
+	"full":       DepthLesson,
+}
+
+// repeatsToDeepen is how many times a rule is explained to a user before
+// its explanations go one level deeper, and twice that for two levels
+const repeatsToDeepen = 2
+
+// lessonExamples is how many example sentences a lesson has
+const lessonExamples = 2
+
+// ParseExplanationDepth returns the depth a name stands for, e.g. "lesson"
+// for "detailed". An empty name is DepthAuto.
+func ParseExplanationDepth(name string) (string, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if key == "" || key == DepthAuto {
+		return DepthAuto, nil
+	}
+	for _, depth := range ExplanationDepths {
+		if key == depth {
+			return depth, nil
+		}
+	}
+	if depth, ok := depthAliases[key]; ok {
+		return depth, nil
+	}
+	return "", fmt.Errorf("unknown explanation depth %q", name)
+}
+
+// DepthForStrictness returns the depth a persona explains at: strict boards
+// only correct, as an examiner would, and gentle practice teaches
+func DepthForStrictness(strictness int) string {
+	switch {
+	case strictness >= 5:
+		return DepthTerse
+	case strictness >= 3:
+		return DepthRule
+	default:
+		return DepthLesson
+	}
+}
+
+// ChooseDepth returns the depth to explain a finding at. A depth the user
+// chose is kept; with DepthAuto it comes from the persona's strictness, one
+// level deeper for every repeatsToDeepen times the rule was explained before.
+func ChooseDepth(chosen string, strictness, explainedBefore int) string {
+	if chosen != "" && chosen != DepthAuto {
+		return chosen
+	}
+	level := depthLevel(DepthForStrictness(strictness)) + explainedBefore/repeatsToDeepen
+	return ExplanationDepths[min(level, len(ExplanationDepths)-1)]
+}
+
+// depthLevel returns the position of a depth in ExplanationDepths
+func depthLevel(depth string) int {
+	for i, d := range ExplanationDepths {
+		if d == depth {
+			return i
+		}
+	}
+	return depthLevel(DepthRule)
+}
+
+// SetDepth explains the finding at depth, filling in the fields the depth
+// shows. The one-line explanations are kept at every depth for clients that
+// don't know about depths.
+func (r *ErrorResult) SetDepth(depth string) {
+	r.Depth = depth
+	r.Correction = correctionLine(r)
+	r.Lesson, r.Examples = "", nil
+	if depth != DepthLesson {
+		return
+	}
+
+	// The user's own sentence is the first example, the rule's follow it
+	if r.Original != r.Corrected && r.Corrected != "" {
+		r.Examples = append(r.Examples, rules.Example{Bad: r.Original, Good: r.Corrected})
+	}
+	if r.rule == nil {
+		r.Lesson = r.ExplanationEnglish
+		return
+	}
+	r.Lesson = r.rule.Lesson
+	for _, example := range r.rule.Examples {
+		if len(r.Examples) == lessonExamples {
+			break
+		}
+		if len(r.Examples) > 0 && strings.EqualFold(example.Bad, r.Examples[0].Bad) {
+			continue
+		}
+		r.Examples = append(r.Examples, example)
+	}
+	if r.Lesson == "" {
+		r.Lesson = r.ExplanationEnglish
+	}
+}
+
+// correctionLine says what to say instead, e.g. "Say 'I have', not 'I has'."
+// Findings that correct nothing are described by their explanation.
+func correctionLine(r *ErrorResult) string {
+	if r.Matched == "" || r.Replacement == "" || r.Matched == r.Replacement {
+		return r.ExplanationEnglish
+	}
+	return fmt.Sprintf("Say '%s', not '%s'.", r.Replacement, r.Matched)
+}
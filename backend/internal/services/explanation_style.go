@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/messages"
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/supabase"
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/translit"
+)
+
+// Explanation styles: how the native-language explanations are written
+const (
+	StyleNative    = messages.NativeStyle // The language in its own script
+	StyleRomanized = "romanized"          // The language in Latin script
+	StyleCodeMixed = "code_mixed"         // The language mixed with English, in Latin script, e.g. Hinglish
+)
+
+// ExplanationStyles lists the explanation styles
+var ExplanationStyles = []string{StyleNative, StyleRomanized, StyleCodeMixed}
+
+// styleAliases are the other names users give the styles
+var styleAliases = map[string]string{
+	"script":   StyleNative,
+	"roman":    StyleRomanized,
+	"latin":    StyleRomanized,
+	"mixed":    StyleCodeMixed,
+	"hinglish": StyleCodeMixed,
+	"tanglish": StyleCodeMixed,
+	"tenglish": StyleCodeMixed,
+}
+
+// ParseExplanationStyle returns the style a name stands for, e.g.
+// "code_mixed" for "Hinglish". An empty name is the default style.
+func ParseExplanationStyle(name string) (string, error) {
+	key := strings.ToLower(strings.TrimSpace(strings.ReplaceAll(name, "-", "_")))
+	if key == "" {
+		return defaultExplanationStyle, nil
+	}
+	for _, style := range ExplanationStyles {
+		if key == style {
+			return style, nil
+		}
+	}
+	if style, ok := styleAliases[key]; ok {
+		return style, nil
+	}
+	return "", fmt.Errorf("unknown explanation style %q", name)
+}
+
+// romanize writes a native-script explanation in Latin script, and reports
+// false if the script is not one translit covers
+func romanize(explanation string) (string, bool) {
+	romanized := translit.Latin(explanation)
+	return romanized, translit.IsLatin(romanized)
+}
+
+// usersTable holds the users, with their explanation_style and explanation_depth
+const usersTable = "users"
+
+// preferencesRetry is how long a user gets the default preferences after
+// reading theirs failed, before the database is asked again
+const preferencesRetry = time.Minute
+
+// UserPreferences keeps each user's explanation style and depth, and how
+// often each rule has been explained to them. With a database the style and
+// depth are saved in the users table; otherwise they, like the history
+// always, last until the server restarts.
+type UserPreferences struct {
+	db        *supabase.Client
+	saved     map[string]*savedPreferences // By user ID
+	explained map[string]map[string]int    // Explanations given, by user ID and rule ID
+	mu        sync.RWMutex
+}
+
+// savedPreferences is a user's row of the users table
+type savedPreferences struct {
+	ExplanationStyle string `json:"explanation_style"`
+	ExplanationDepth string `json:"explanation_depth"`
+
+	retryAt time.Time // When to read them again after a failed read, zero once read
+}
+
+// NewUserPreferences creates a preference store; db may be nil
+func NewUserPreferences(db *supabase.Client) *UserPreferences {
+	return &UserPreferences{
+		db:        db,
+		saved:     make(map[string]*savedPreferences),
+		explained: make(map[string]map[string]int),
+	}
+}
+
+// ExplanationStyle returns the style a user chose, or the default style
+func (up *UserPreferences) ExplanationStyle(ctx context.Context, userID string) string {
+	return up.load(ctx, userID).ExplanationStyle
+}
+
+// ExplanationDepth returns the depth a user chose, or DepthAuto
+func (up *UserPreferences) ExplanationDepth(ctx context.Context, userID string) string {
+	return up.load(ctx, userID).ExplanationDepth
+}
+
+// load returns a user's preferences, reading them from the database the
+// first time. Values the database lacks or doesn't know are the defaults,
+// and so are all of them for preferencesRetry after a failed read.
+func (up *UserPreferences) load(ctx context.Context, userID string) savedPreferences {
+	up.mu.RLock()
+	saved, ok := up.saved[userID]
+	up.mu.RUnlock()
+	if ok && (saved.retryAt.IsZero() || time.Now().Before(saved.retryAt)) {
+		return *saved
+	}
+
+	saved = &savedPreferences{ExplanationStyle: defaultExplanationStyle, ExplanationDepth: DepthAuto}
+	if up.db != nil && userID != "" {
+		var rows []savedPreferences
+		query := url.Values{
+			"id":     {"eq." + userID},
+			"select": {"explanation_style,explanation_depth"},
+		}
+		if err := up.db.Select(ctx, usersTable, query, &rows); err != nil {
+			log.Printf("Error reading preferences of user %s: %v", userID, err)
+			saved.retryAt = time.Now().Add(preferencesRetry)
+		} else if len(rows) > 0 {
+			if style, err := ParseExplanationStyle(rows[0].ExplanationStyle); err == nil {
+				saved.ExplanationStyle = style
+			}
+			if depth, err := ParseExplanationDepth(rows[0].ExplanationDepth); err == nil {
+				saved.ExplanationDepth = depth
+			}
+		}
+	}
+
+	up.mu.Lock()
+	up.saved[userID] = saved
+	up.mu.Unlock()
+	return *saved
+}
+
+// SetExplanationStyle saves the style a user chose
+func (up *UserPreferences) SetExplanationStyle(ctx context.Context, userID, style string) error {
+	return up.save(ctx, userID, "explanation_style", style, func(saved *savedPreferences) {
+		saved.ExplanationStyle = style
+	})
+}
+
+// SetExplanationDepth saves the depth a user chose, DepthAuto to let the
+// persona and their history decide
+func (up *UserPreferences) SetExplanationDepth(ctx context.Context, userID, depth string) error {
+	return up.save(ctx, userID, "explanation_depth", depth, func(saved *savedPreferences) {
+		saved.ExplanationDepth = depth
+	})
+}
+
+// save writes one preference of a user to the database and applies it to
+// the ones kept in memory
+func (up *UserPreferences) save(ctx context.Context, userID, column, value string, apply func(*savedPreferences)) error {
+	if up.db != nil {
+		filter := url.Values{"id": {"eq." + userID}}
+		if err := up.db.Update(ctx, usersTable, filter, map[string]string{column: value}); err != nil {
+			return err
+		}
+	}
+
+	saved := up.load(ctx, userID)
+	apply(&saved)
+	up.mu.Lock()
+	up.saved[userID] = &saved
+	up.mu.Unlock()
+	return nil
+}
+
+// RecordExplained notes that a rule was explained to a user and returns how
+// many times it had been before
+func (up *UserPreferences) RecordExplained(userID, ruleID string) int {
+	up.mu.Lock()
+	defer up.mu.Unlock()
+
+	counts, ok := up.explained[userID]
+	if !ok {
+		counts = make(map[string]int)
+		up.explained[userID] = counts
+	}
+	before := counts[ruleID]
+	counts[ruleID]++
+	return before
+}
+
+// Explained returns how many times a rule has been explained to a user
+func (up *UserPreferences) Explained(userID, ruleID string) int {
+	up.mu.RLock()
+	defer up.mu.RUnlock()
+	return up.explained[userID][ruleID]
+}
@@ -0,0 +1,381 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// longPauseMs is the shortest silence counted as a long pause
+	longPauseMs = 3000
+
+	// assumedWordsPerMinute estimates speaking time when too little time has
+	// passed between utterances to measure it
+	assumedWordsPerMinute = 130
+)
+
+// FluencyAnalyzer tracks disfluencies in a session's speech: fillers,
+// repeated words, false starts, self-corrections and long pauses. They are
+// reported as live metrics and never interrupt the speaker the way grammar
+// errors do.
+type FluencyAnalyzer struct {
+	sessions map[string]*FluencySession
+	mu       sync.RWMutex
+}
+
+// FluencySession holds the running counts for one session
+type FluencySession struct {
+	sessionID       string
+	firstSpeech     time.Time
+	lastSpeech      time.Time
+	wordCount       int
+	fillers         map[string]int // Count per filler, e.g. "umm": 3
+	fillerCount     int
+	repeatedWords   int
+	falseStarts     int
+	selfCorrections int
+	longPauses      int
+	longestPauseMs  float64
+	mu              sync.Mutex
+}
+
+// FluencyEvent is a single disfluency found in an utterance or pause
+type FluencyEvent struct {
+	Type         string  `json:"type"` // filler, repetition, false_start, self_correction or long_pause
+	Text         string  `json:"text,omitempty"`
+	WordPosition int     `json:"word_position"`
+	DurationMs   float64 `json:"duration_ms,omitempty"` // Length of a long pause
+}
+
+// Fluency event types
+const (
+	FluencyFiller         = "filler"
+	FluencyRepetition     = "repetition"
+	FluencyFalseStart     = "false_start"
+	FluencySelfCorrection = "self_correction"
+	FluencyLongPause      = "long_pause"
+)
+
+// FluencyMetrics summarizes the fluency of a session so far
+type FluencyMetrics struct {
+	WordCount        int            `json:"word_count"`
+	SpeakingMinutes  float64        `json:"speaking_minutes"`
+	FillerCount      int            `json:"filler_count"`
+	FillersPerMinute float64        `json:"fillers_per_minute"`
+	Fillers          map[string]int `json:"fillers"`
+	RepeatedWords    int            `json:"repeated_words"`
+	FalseStarts      int            `json:"false_starts"`
+	SelfCorrections  int            `json:"self_corrections"`
+	LongPauses       int            `json:"long_pauses"`
+	LongestPauseMs   float64        `json:"longest_pause_ms"`
+}
+
+// FluencyUpdate is what one utterance or pause added, with the new totals
+type FluencyUpdate struct {
+	Events  []FluencyEvent `json:"events"`
+	Metrics FluencyMetrics `json:"metrics"`
+}
+
+// NewFluencyAnalyzer creates a new fluency analyzer
+func NewFluencyAnalyzer() *FluencyAnalyzer {
+	return &FluencyAnalyzer{
+		sessions: make(map[string]*FluencySession),
+	}
+}
+
+// StartSession initializes fluency tracking for a session
+func (fa *FluencyAnalyzer) StartSession(sessionID string) {
+	fa.mu.Lock()
+	defer fa.mu.Unlock()
+
+	fa.sessions[sessionID] = &FluencySession{
+		sessionID: sessionID,
+		fillers:   make(map[string]int),
+	}
+}
+
+// EndSession removes a session from memory
+func (fa *FluencyAnalyzer) EndSession(sessionID string) {
+	fa.mu.Lock()
+	defer fa.mu.Unlock()
+
+	delete(fa.sessions, sessionID)
+}
+
+// session returns the session, creating it if needed
+func (fa *FluencyAnalyzer) session(sessionID string) *FluencySession {
+	fa.mu.RLock()
+	session, exists := fa.sessions[sessionID]
+	fa.mu.RUnlock()
+
+	if !exists {
+		fa.StartSession(sessionID)
+		fa.mu.RLock()
+		session = fa.sessions[sessionID]
+		fa.mu.RUnlock()
+	}
+	return session
+}
+
+// AnalyzeUtterance records the disfluencies in a final transcript
+func (fa *FluencyAnalyzer) AnalyzeUtterance(sessionID, text string) *FluencyUpdate {
+	session := fa.session(sessionID)
+	events := FindDisfluencies(text)
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	now := time.Now()
+	if session.firstSpeech.IsZero() {
+		session.firstSpeech = now
+	}
+	session.lastSpeech = now
+	session.wordCount += len(strings.Fields(text))
+
+	for _, event := range events {
+		switch event.Type {
+		case FluencyFiller:
+			session.fillerCount++
+			session.fillers[event.Text]++
+		case FluencyRepetition:
+			session.repeatedWords++
+		case FluencyFalseStart:
+			session.falseStarts++
+		case FluencySelfCorrection:
+			session.selfCorrections++
+		}
+	}
+
+	return &FluencyUpdate{Events: events, Metrics: session.metrics()}
+}
+
+// RecordPause records a silence reported by the client. It returns nil for
+// pauses shorter than a long pause.
+func (fa *FluencyAnalyzer) RecordPause(sessionID string, durationMs float64) *FluencyUpdate {
+	if durationMs < longPauseMs {
+		return nil
+	}
+	session := fa.session(sessionID)
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	session.longPauses++
+	session.longestPauseMs = max(session.longestPauseMs, durationMs)
+
+	event := FluencyEvent{Type: FluencyLongPause, WordPosition: session.wordCount, DurationMs: durationMs}
+	return &FluencyUpdate{Events: []FluencyEvent{event}, Metrics: session.metrics()}
+}
+
+// GetMetrics returns the fluency metrics of a session, or nil if there is
+// no such session
+func (fa *FluencyAnalyzer) GetMetrics(sessionID string) *FluencyMetrics {
+	fa.mu.RLock()
+	session, exists := fa.sessions[sessionID]
+	fa.mu.RUnlock()
+
+	if !exists {
+		return nil
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	metrics := session.metrics()
+	return &metrics
+}
+
+// metrics computes the totals. Caller must hold session.mu.
+func (s *FluencySession) metrics() FluencyMetrics {
+	fillers := make(map[string]int, len(s.fillers))
+	for filler, count := range s.fillers {
+		fillers[filler] = count
+	}
+
+	// Wall-clock time between the first and the last utterance, or the
+	// time the words would take at a typical pace if that is longer
+	minutes := s.lastSpeech.Sub(s.firstSpeech).Minutes()
+	minutes = max(minutes, float64(s.wordCount)/assumedWordsPerMinute)
+
+	fillersPerMinute := 0.0
+	if minutes > 0 {
+		fillersPerMinute = float64(s.fillerCount) / minutes
+	}
+
+	return FluencyMetrics{
+		WordCount:        s.wordCount,
+		SpeakingMinutes:  minutes,
+		FillerCount:      s.fillerCount,
+		FillersPerMinute: fillersPerMinute,
+		Fillers:          fillers,
+		RepeatedWords:    s.repeatedWords,
+		FalseStarts:      s.falseStarts,
+		SelfCorrections:  s.selfCorrections,
+		LongPauses:       s.longPauses,
+		LongestPauseMs:   s.longestPauseMs,
+	}
+}
+
+var (
+	// fillerWords are hesitation sounds, always fillers
+	fillerWords = wordSet(`um umm ummm uh uhh uhhh er erm ah hmm mm`)
+
+	// fillerLikeBefore and notFillerLikeAfter separate the filler "like"
+	// ("it was like really hard") from the verb and the preposition ("it
+	// was like a dream")
+	fillerLikeBefore   = wordSet(`and so but just was is were it's`)
+	notFillerLikeAfter = wordSet(`a an the this that these those my your his her our their to me him us them it`)
+
+	// youKnowObjects and youKnowBefore mark "you know" used as a verb:
+	// "you know the answer", "do you know him"
+	youKnowObjects = wordSet(`the what how that him her them me it about why where who when a an this my your his their our`)
+	youKnowBefore  = wordSet(`do did don't didn't if as`)
+
+	// fillerLikeIntensifiers follow a filler "like" ("like really hard")
+	// unless a subject comes before it ("I like very spicy food")
+	fillerLikeIntensifiers = wordSet(`really very so totally super literally basically just`)
+	likeSubjects           = wordSet(`i you we they he she who would also still don't didn't`)
+
+	// intendedRepeats are doubled on purpose or by grammar: "very very
+	// good", "he said that that was fine"
+	intendedRepeats = wordSet(`very really so no yes bye please many much long far ha that had`)
+
+	// sorryBefore marks "sorry" as an apology rather than a self-correction
+	sorryBefore = wordSet(`i'm am so very really feel`)
+)
+
+// selfCorrections are phrases that announce a repair, longest first
+var selfCorrections = [][]string{
+	{"let", "me", "rephrase"},
+	{"what", "i", "meant"},
+	{"i", "meant"},
+	{"no", "wait"},
+	{"or", "rather"},
+	{"actually", "no"},
+	{"i", "mean"},
+	{"sorry"},
+}
+
+// FindDisfluencies lists the fillers, repetitions, false starts and
+// self-corrections in an utterance. Word positions index strings.Fields(text).
+func FindDisfluencies(text string) []FluencyEvent {
+	raw := strings.Fields(text)
+	words := make([]string, len(raw))
+	for i, word := range raw {
+		words[i] = strings.ToLower(strings.Trim(word, `.,;:!?"()`))
+	}
+
+	events := make([]FluencyEvent, 0)
+	for i := 0; i < len(words); {
+		word := words[i]
+
+		// A cut-off word such as "wh-" or "to the—"
+		if trimmed := strings.TrimRight(raw[i], `.,;:!?"`); i+1 < len(words) &&
+			(strings.HasSuffix(trimmed, "-") || strings.HasSuffix(trimmed, "—") || strings.HasSuffix(trimmed, "–")) {
+			events = append(events, FluencyEvent{Type: FluencyFalseStart, Text: trimmed, WordPosition: i})
+			i++
+			continue
+		}
+
+		if n := matchPhrase(words, i, selfCorrections); n > 0 && !(n == 1 && (i == 0 || sorryBefore[words[i-1]])) {
+			phrase := strings.Join(words[i:i+n], " ")
+			eventType := FluencySelfCorrection
+			if phrase == "i mean" && i == 0 {
+				// "I mean, ..." opening an answer repairs nothing
+				eventType = FluencyFiller
+			}
+			events = append(events, FluencyEvent{Type: eventType, Text: phrase, WordPosition: i})
+			i += n
+			continue
+		}
+
+		if word == "you" && i+1 < len(words) && words[i+1] == "know" {
+			asVerb := (i+2 < len(words) && youKnowObjects[words[i+2]]) || (i > 0 && youKnowBefore[words[i-1]])
+			if !asVerb {
+				events = append(events, FluencyEvent{Type: FluencyFiller, Text: "you know", WordPosition: i})
+				i += 2
+				continue
+			}
+		}
+
+		if fillerWords[word] || isFillerLike(raw, words, i) {
+			events = append(events, FluencyEvent{Type: FluencyFiller, Text: word, WordPosition: i})
+			i++
+			continue
+		}
+
+		// A restarted phrase: "I went to, I went to the market"
+		if n := repeatedPhrase(words, i); n > 0 {
+			events = append(events, FluencyEvent{Type: FluencyFalseStart, Text: strings.Join(words[i:i+n], " "), WordPosition: i})
+			i += n
+			continue
+		}
+
+		if word != "" && i+1 < len(words) && words[i+1] == word && !intendedRepeats[word] {
+			events = append(events, FluencyEvent{Type: FluencyRepetition, Text: word, WordPosition: i})
+		}
+		i++
+	}
+	return events
+}
+
+// matchPhrase returns the length of the first phrase starting at words[i],
+// or 0 if none does
+func matchPhrase(words []string, i int, phrases [][]string) int {
+	for _, phrase := range phrases {
+		if i+len(phrase) > len(words) {
+			continue
+		}
+		matched := true
+		for j, word := range phrase {
+			if words[i+j] != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return len(phrase)
+		}
+	}
+	return 0
+}
+
+// repeatedPhrase returns the length of a 2 to 4 word phrase at words[i]
+// that is immediately said again, or 0
+func repeatedPhrase(words []string, i int) int {
+	for n := 4; n >= 2; n-- {
+		if i+2*n > len(words) {
+			continue
+		}
+		if strings.Join(words[i:i+n], " ") == strings.Join(words[i+n:i+2*n], " ") {
+			return n
+		}
+	}
+	return 0
+}
+
+// isFillerLike reports whether words[i] is "like" used as a filler
+func isFillerLike(raw, words []string, i int) bool {
+	if words[i] != "like" {
+		return false
+	}
+	if strings.HasSuffix(raw[i], ",") || (i > 0 && strings.HasSuffix(raw[i-1], ",")) {
+		return true
+	}
+	if i == 0 || i+1 >= len(words) {
+		return false
+	}
+	if fillerLikeIntensifiers[words[i+1]] && !likeSubjects[words[i-1]] {
+		return true
+	}
+	return fillerLikeBefore[words[i-1]] && !notFillerLikeAfter[words[i+1]]
+}
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
@@ -1,16 +1,25 @@
 package services
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"log"
+	"slices"
+	"sort"
 	"strings"
+	"time"
+	"unicode"
 
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/messages"
 	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/rules"
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/translit"
 )
 
 // GrammarDetector handles real-time grammar detection
 type GrammarDetector struct {
-	llmRouter *LLMRouter
+	llmRouter    *LLMRouter
+	messages     *messages.Catalogue // Native-language explanations of the rules
+	translations *TranslationCache   // LLM translations of what the catalogue lacks
 }
 
 // ErrorResult represents a detected error
@@ -22,125 +31,621 @@ type ErrorResult struct {
 	ExplanationNative string `json:"explanation_native"`
 	RuleID            string `json:"rule_id"`
 	Confidence        float64 `json:"confidence"`
+	Category          string  `json:"category"`
+	Severity          string  `json:"severity"`
+	Source            string  `json:"source"` // SourceRules, SourceLLM or SourceBoth
+
+	// Span of the error inside Original
+	Start       int          `json:"start"`
+	End         int          `json:"end"`
+	WordStart   int          `json:"word_start"`
+	WordEnd     int          `json:"word_end"`
+	Matched     string       `json:"matched"`
+	Replacement string       `json:"replacement"`
+	Edits       []rules.Edit `json:"edits"` // Changes to Original that fix this error
+
+	// How much the finding is explained, see SetDepth
+	Depth      string          `json:"depth,omitempty"`
+	Correction string          `json:"correction,omitempty"` // What to say instead, the whole of a terse explanation
+	Lesson     string          `json:"lesson,omitempty"`     // The grammar behind the rule, at DepthLesson
+	Examples   []rules.Example `json:"examples,omitempty"`   // Example sentences, at DepthLesson
+
+	rule     *rules.GrammarRule // Rule that found the error, nil for LLM findings
+	unplaced bool               // LLM finding whose words were not found in Original
 }
 
-// NewGrammarDetector creates a new grammar detector
-func NewGrammarDetector(llmRouter *LLMRouter) *GrammarDetector {
+// Sources of a finding
+const (
+	SourceRules = "rules"     // Found by a grammar rule
+	SourceLLM   = "llm"       // Found by the LLM
+	SourceBoth  = "rules+llm" // Found by a rule and confirmed by the LLM
+)
+
+// LLMRuleID is the rule ID of findings made by the LLM
+const LLMRuleID = "LLM_DETECTED"
+
+// LLM findings never outrank a rule at its default confidence, only rules
+// whose confidence was lowered because they often misfire
+const (
+	defaultLLMConfidence = 0.85
+	minLLMConfidence     = 0.5
+	maxLLMConfidence     = 0.9
+)
+
+// NewGrammarDetector creates a new grammar detector; translations may be nil
+func NewGrammarDetector(llmRouter *LLMRouter, translations *TranslationCache) *GrammarDetector {
 	return &GrammarDetector{
-		llmRouter: llmRouter,
+		llmRouter:    llmRouter,
+		messages:     messages.Default(),
+		translations: translations,
+	}
+}
+
+// DetectOptions controls how grammar errors are detected and explained
+type DetectOptions struct {
+	NativeLanguage string // Selects rule packs and the explanation language, e.g. "Hindi"
+	Profile        string // Rule profile, empty for the default profile
+	Style          string // Explanation style, empty for the native script
+
+	// Merge asks the LLM even when rules matched and merges its findings
+	// with theirs. Without it the LLM is only a fallback for texts no rule
+	// flagged.
+	Merge bool
+
+	// Budget is how long to wait for the LLM before returning the rule
+	// findings alone; zero waits as long as ctx allows. LLM findings that
+	// arrive later are passed to OnLate, from another goroutine, merged
+	// with the rule findings already returned.
+	Budget time.Duration
+	OnLate func(results []*ErrorResult)
+}
+
+// DefaultLLMBudget is how long live sessions wait for the LLM before
+// answering with the rule findings
+const DefaultLLMBudget = 250 * time.Millisecond
+
+// DetectGrammarError checks for grammar errors with < 5ms latency for rule-based detection.
+// The rule packs for nativeLanguage run on top of the base rules.
+// It returns the first error in the text; use DetectGrammarErrors for all of them.
+func (gd *GrammarDetector) DetectGrammarError(ctx context.Context, text string, nativeLanguage string) (*ErrorResult, error) {
+	results, err := gd.DetectGrammarErrors(ctx, text, DetectOptions{NativeLanguage: nativeLanguage})
+	if err != nil || len(results) == 0 {
+		return nil, err
 	}
+	return results[0], nil
 }
 
-// DetectGrammarError checks for grammar errors with < 5ms latency for rule-based detection
-func (gd *GrammarDetector) DetectGrammarError(text string, nativeLanguage string) (*ErrorResult, error) {
+// DetectGrammarErrors returns every grammar error in the text, ordered by position
+func (gd *GrammarDetector) DetectGrammarErrors(ctx context.Context, text string, opts DetectOptions) ([]*ErrorResult, error) {
+	budgetCtx := ctx
+	if opts.Budget > 0 {
+		var cancel context.CancelFunc
+		budgetCtx, cancel = context.WithTimeout(ctx, opts.Budget)
+		defer cancel()
+	}
+
 	// First, try rule-based detection (ultra-fast, ~1-5ms)
-	if rule, corrected := rules.DetectError(text); rule != nil {
-		explanation := gd.generateNativeExplanation(rule.Description, nativeLanguage)
-		
-		return &ErrorResult{
-			Original:          text,
-			Corrected:         corrected,
-			ErrorType:         rule.ErrorType,
-			ExplanationEnglish: rule.Description,
-			ExplanationNative: explanation,
-			RuleID:            rule.ID,
-			Confidence:        0.95,
-		}, nil
+	results := gd.detectWithRules(text, opts)
+
+	// The LLM finds complex errors, but only in text long enough to have them
+	if (len(results) > 0 && !opts.Merge) || len(strings.Fields(text)) < 5 {
+		gd.explainResults(budgetCtx, results, opts.NativeLanguage, opts.Style)
+		return results, nil
+	}
+
+	type llmAnswer struct {
+		results []*ErrorResult
+		err     error
+	}
+	answer := make(chan llmAnswer, 1)
+	go func() {
+		llmResults, err := gd.detectWithLLM(ctx, text, opts.NativeLanguage, opts.Style)
+		answer <- llmAnswer{llmResults, err}
+	}()
+
+	gd.explainResults(budgetCtx, results, opts.NativeLanguage, opts.Style)
+
+	select {
+	case a := <-answer:
+		return mergeLLMFindings(text, results, a.results, a.err)
+	case <-budgetCtx.Done():
+	}
+
+	if ctx.Err() != nil {
+		return results, nil
+	}
+
+	// Answer with the rule findings now and follow up with the LLM's
+	if opts.OnLate != nil {
+		ruleResults := make([]*ErrorResult, 0, len(results))
+		for _, result := range results {
+			copied := *result
+			ruleResults = append(ruleResults, &copied)
+		}
+
+		go func() {
+			a := <-answer
+			late, err := mergeLLMFindings(text, ruleResults, a.results, a.err)
+			if err != nil {
+				log.Printf("Late LLM grammar check failed: %v", err)
+				return
+			}
+			if ctx.Err() == nil && slices.ContainsFunc(late, func(result *ErrorResult) bool {
+				return result.Source == SourceLLM
+			}) {
+				opts.OnLate(late)
+			}
+		}()
+	}
+
+	return results, nil
+}
+
+// mergeLLMFindings merges the answer of the LLM into the rule findings.
+// A failed LLM call only matters when the rules found nothing.
+func mergeLLMFindings(text string, results, llmResults []*ErrorResult, err error) ([]*ErrorResult, error) {
+	if err != nil {
+		if len(results) > 0 {
+			log.Printf("LLM grammar check failed, using rule findings only: %v", err)
+			return results, nil
+		}
+		return nil, err
+	}
+	return MergeFindings(text, results, llmResults), nil
+}
+
+// detectWithRules returns the findings of the grammar rules, without
+// native-language explanations
+func (gd *GrammarDetector) detectWithRules(text string, opts DetectOptions) []*ErrorResult {
+	matches := rules.DetectAllWith(text, rules.Options{
+		Profile:        opts.Profile,
+		NativeLanguage: opts.NativeLanguage,
+	})
+	if len(matches) == 0 {
+		return nil
+	}
+
+	corrected := rules.ApplyMatches(text, matches)
+
+	results := make([]*ErrorResult, 0, len(matches))
+	for _, m := range matches {
+		results = append(results, &ErrorResult{
+			Original:           text,
+			Corrected:          corrected,
+			ErrorType:          m.Rule.ErrorType,
+			ExplanationEnglish: m.Explanation(),
+			RuleID:             m.Rule.ID,
+			Confidence:         m.Rule.Confidence,
+			Category:           m.Rule.Category,
+			Severity:           string(m.Rule.Severity),
+			Source:             SourceRules,
+			Start:              m.Start,
+			End:                m.End,
+			WordStart:          m.WordStart,
+			WordEnd:            m.WordEnd,
+			Matched:            m.Text,
+			Replacement:        m.Replacement,
+			Edits:              m.Edits,
+			rule:               m.Rule,
+		})
+	}
+	return results
+}
+
+// explainResults fills in the native-language explanations in style. Rule
+// findings come from the message catalogue; LLM findings, findings in
+// languages the catalogue lacks and rules it has no message of the style
+// for are translated by the LLM, each distinct English explanation once and
+// then from the translation cache.
+func (gd *GrammarDetector) explainResults(ctx context.Context, results []*ErrorResult, nativeLanguage, style string) {
+	if style == "" {
+		style = defaultExplanationStyle
+	}
+
+	explanations := make(map[string]string)
+	for _, result := range results {
+		if explanation, ok := gd.catalogueExplanation(result, nativeLanguage, style); ok {
+			result.ExplanationNative = explanation
+			continue
+		}
+		if result.RuleID != LLMRuleID && style == StyleNative && gd.messages.Language(nativeLanguage) != nil {
+			// English ends every fallback chain; rules are not translated live
+			result.ExplanationNative = result.ExplanationEnglish
+			continue
+		}
+
+		explanation, ok := explanations[result.ExplanationEnglish]
+		if !ok {
+			explanation = gd.translateExplanation(ctx, result.ExplanationEnglish, result.RuleID, nativeLanguage, style)
+			explanations[result.ExplanationEnglish] = explanation
+		}
+		result.ExplanationNative = explanation
+	}
+}
+
+// catalogueExplanation renders a finding's explanation from the message
+// catalogue. Without a message written in the romanized style the native
+// one is transliterated, if its script allows.
+func (gd *GrammarDetector) catalogueExplanation(result *ErrorResult, nativeLanguage, style string) (string, bool) {
+	args := messageArgs(result)
+	if message, ok := gd.messages.ExplainStyle(nativeLanguage, style, result.RuleID, args); ok {
+		return message.Text, true
+	}
+	if style == StyleRomanized {
+		if message, ok := gd.messages.Explain(nativeLanguage, result.RuleID, args); ok {
+			return romanize(message.Text)
+		}
+	}
+	return "", false
+}
+
+// messageArgs fills the placeholders of a catalogue message from a finding
+func messageArgs(result *ErrorResult) messages.Args {
+	return messages.Args{
+		Original:          result.Matched,
+		Corrected:         result.Replacement,
+		Sentence:          result.Original,
+		CorrectedSentence: result.Corrected,
+	}
+}
+
+// MostSevere returns the most severe error in results, the earliest one on a tie
+func MostSevere(results []*ErrorResult) *ErrorResult {
+	var worst *ErrorResult
+	for _, result := range results {
+		if worst == nil || rules.Severity(result.Severity).Rank() > rules.Severity(worst.Severity).Rank() {
+			worst = result
+		}
+	}
+	return worst
+}
+
+// MergeFindings combines the rule and LLM findings for one text. Findings
+// whose spans overlap describe the same error: when they make the same
+// correction the rule finding is kept, marked as confirmed by the LLM, with
+// the combined confidence; when they disagree the more confident one wins
+// and a tie goes to the rule. An LLM finding the model could not place in
+// the text is only kept when nothing else was found. The result is ordered
+// by position and Corrected applies every kept finding.
+func MergeFindings(text string, ruleResults, llmResults []*ErrorResult) []*ErrorResult {
+	merged := make([]*ErrorResult, 0, len(ruleResults)+len(llmResults))
+	merged = append(merged, ruleResults...)
+	unplaced := make([]*ErrorResult, 0)
+
+	for _, finding := range llmResults {
+		if finding.unplaced {
+			unplaced = append(unplaced, finding)
+			continue
+		}
+
+		overlapping := make([]*ErrorResult, 0)
+		agreed := false
+		for _, other := range merged {
+			if !spansOverlap(finding, other) {
+				continue
+			}
+			overlapping = append(overlapping, other)
+			if sameCorrection(text, finding, other) {
+				confirmFinding(other, finding)
+				agreed = true
+			}
+		}
+
+		switch {
+		case agreed:
+		case len(overlapping) == 0:
+			merged = append(merged, finding)
+		case finding.Confidence > maxConfidence(overlapping):
+			for _, other := range overlapping {
+				log.Printf("LLM overrides %s on %q (%.2f > %.2f)", other.RuleID, text, finding.Confidence, other.Confidence)
+			}
+			merged = append(removeFindings(merged, overlapping), finding)
+		default:
+			log.Printf("Dropped LLM finding %q on %q, a more confident finding overlaps it", finding.Matched, text)
+		}
 	}
 
-	// If no rule matched and text is long enough, use LLM fallback for complex errors
-	if len(strings.Fields(text)) >= 5 {
-		return gd.detectWithLLM(text, nativeLanguage)
+	if len(merged) == 0 && len(unplaced) > 0 {
+		merged = append(merged, unplaced[0])
+	}
+
+	sort.SliceStable(merged, func(a, b int) bool {
+		return merged[a].Start < merged[b].Start
+	})
+
+	groups := make([][]rules.Edit, 0, len(merged))
+	for _, result := range merged {
+		groups = append(groups, result.Edits)
+	}
+	corrected := rules.ApplyEdits(text, groups)
+	for _, result := range merged {
+		result.Corrected = corrected
 	}
 
-	return nil, nil
+	return merged
+}
+
+// spansOverlap reports whether two findings cover some of the same text.
+// Insertions have empty spans and overlap anything at the same position.
+func spansOverlap(a, b *ErrorResult) bool {
+	return a.Start == b.Start || (a.Start < b.End && b.Start < a.End)
+}
+
+// sameCorrection reports whether two findings fix the text the same way
+func sameCorrection(text string, a, b *ErrorResult) bool {
+	return strings.EqualFold(
+		rules.ApplyEdits(text, [][]rules.Edit{a.Edits}),
+		rules.ApplyEdits(text, [][]rules.Edit{b.Edits}),
+	)
+}
+
+// confirmFinding records that finding agrees with the rule finding kept,
+// treating the two as independent evidence for the error
+func confirmFinding(kept, finding *ErrorResult) {
+	if kept.Source != SourceRules {
+		return
+	}
+	kept.Source = SourceBoth
+	kept.Confidence = min(1-(1-kept.Confidence)*(1-finding.Confidence), 0.99)
+}
+
+// maxConfidence returns the highest confidence among results
+func maxConfidence(results []*ErrorResult) float64 {
+	highest := 0.0
+	for _, result := range results {
+		highest = max(highest, result.Confidence)
+	}
+	return highest
+}
+
+// removeFindings returns results without the findings in drop
+func removeFindings(results, drop []*ErrorResult) []*ErrorResult {
+	kept := results[:0]
+	for _, result := range results {
+		if !slices.Contains(drop, result) {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
+
+// llmFinding is one error reported by the LLM
+type llmFinding struct {
+	Original    string  `json:"original"`
+	Correction  string  `json:"correction"`
+	ErrorType   string  `json:"error_type"`
+	Explanation string  `json:"explanation"`
+	Confidence  float64 `json:"confidence"`
+}
+
+// grammarSchema is the JSON detectWithLLM asks the LLM for
+var grammarSchema = &Schema{
+	Type:     "object",
+	Required: []string{"corrected", "errors"},
+	Properties: map[string]*Schema{
+		"corrected": {Type: "string"},
+		"errors": {
+			Type: "array",
+			Items: &Schema{
+				Type:     "object",
+				Required: []string{"original", "correction", "explanation"},
+				Properties: map[string]*Schema{
+					"original":    {Type: "string"},
+					"correction":  {Type: "string"},
+					"error_type":  {Type: "string"},
+					"explanation": {Type: "string"},
+					"confidence":  {Type: "number", Minimum: floatPtr(0), Maximum: floatPtr(1)},
+				},
+			},
+		},
+	},
+}
+
+// floatPtr returns a pointer to v, for schema bounds
+func floatPtr(v float64) *float64 {
+	return &v
 }
 
 // detectWithLLM uses LLM for complex grammar detection
-func (gd *GrammarDetector) detectWithLLM(text string, nativeLanguage string) (*ErrorResult, error) {
-	prompt := fmt.Sprintf(`Analyze this English text for grammar errors: "%s"
+func (gd *GrammarDetector) detectWithLLM(ctx context.Context, text string, nativeLanguage, style string) ([]*ErrorResult, error) {
+	prompt := fmt.Sprintf(`Find the grammar errors in this English text spoken by a learner:
+%s
+
+%s
 
-If there's a grammar error:
-1. Provide the corrected version
-2. Explain the error type
-3. Give a brief explanation
+Ignore filler words, repetitions and false starts; they are not grammar errors.
+For every error, copy the wrong words exactly as they appear in the text and
+give the words that should replace them.
 
 Respond in JSON format:
 {
-  "has_error": true/false,
-  "corrected": "corrected text",
-  "error_type": "type of error",
-  "explanation": "brief explanation"
-}`, text)
+  "corrected": "the whole text with every error fixed",
+  "errors": [
+    {
+      "original": "the wrong words, copied from the text",
+      "correction": "the words that replace them",
+      "error_type": "type of error",
+      "explanation": "brief explanation",
+      "confidence": 0.0 to 1.0
+    }
+  ]
+}
 
-	response, err := gd.llmRouter.Generate(prompt)
-	if err != nil {
-		return nil, err
-	}
+Return an empty errors list if the text is correct.`, quoteUserText(text), userTextInstruction)
 
 	var llmResult struct {
-		HasError    bool   `json:"has_error"`
-		Corrected   string `json:"corrected"`
-		ErrorType   string `json:"error_type"`
-		Explanation string `json:"explanation"`
+		Corrected string       `json:"corrected"`
+		Errors    []llmFinding `json:"errors"`
 	}
 
-	if err := json.Unmarshal([]byte(response), &llmResult); err != nil {
+	if err := gd.llmRouter.GenerateStructured(ctx, prompt, grammarSchema, &llmResult); err != nil {
 		return nil, err
 	}
 
-	if !llmResult.HasError {
-		return nil, nil
+	// A correction far from what was said means the model was steered by
+	// the text or rewrote it; findings that rely on it are dropped
+	if llmResult.Corrected != "" && llmResult.Corrected != text {
+		if err := checkCorrection(text, llmResult.Corrected); err != nil {
+			log.Printf("Rejected LLM correction %q -> %q: %v", text, llmResult.Corrected, err)
+			llmResult.Corrected = ""
+		}
 	}
 
-	explanation := gd.generateNativeExplanation(llmResult.Explanation, nativeLanguage)
+	results := make([]*ErrorResult, 0, len(llmResult.Errors))
+	taken := make([]bool, len(text))
+
+	for _, finding := range llmResult.Errors {
+		result := &ErrorResult{
+			Original:           text,
+			ErrorType:          finding.ErrorType,
+			ExplanationEnglish: finding.Explanation,
+			RuleID:             LLMRuleID,
+			Confidence:         llmConfidence(finding.Confidence),
+			Category:           "llm",
+			Severity:           string(rules.SeverityMajor),
+			Source:             SourceLLM,
+		}
 
-	return &ErrorResult{
-		Original:          text,
-		Corrected:         llmResult.Corrected,
-		ErrorType:         llmResult.ErrorType,
-		ExplanationEnglish: llmResult.Explanation,
-		ExplanationNative: explanation,
-		RuleID:            "LLM_DETECTED",
-		Confidence:        0.85,
-	}, nil
+		original := strings.TrimSpace(finding.Original)
+		start := locateSpan(text, original, taken)
+		if start < 0 {
+			// The model reworded the text, so the error covers all of it
+			if llmResult.Corrected == "" || llmResult.Corrected == text {
+				continue
+			}
+			result.unplaced = true
+			result.End = len(text)
+			result.Replacement = llmResult.Corrected
+			result.Edits = rules.Diff(text, llmResult.Corrected)
+		} else {
+			corrected := text[:start] + finding.Correction + text[start+len(original):]
+			if err := checkCorrection(text, corrected); err != nil {
+				log.Printf("Rejected LLM correction %q -> %q in %q: %v", original, finding.Correction, text, err)
+				continue
+			}
+			result.Start = start
+			result.End = start + len(original)
+			result.Replacement = finding.Correction
+			result.Edits = rules.DiffSpan(text, result.Start, result.End, finding.Correction)
+			if len(result.Edits) == 0 {
+				continue
+			}
+			for i := result.Start; i < result.End; i++ {
+				taken[i] = true
+			}
+		}
+		result.Matched = text[result.Start:result.End]
+		result.WordStart, result.WordEnd = rules.WordRange(text, result.Start, result.End)
+		results = append(results, result)
+	}
+
+	gd.explainResults(ctx, results, nativeLanguage, style)
+	return results, nil
 }
 
-// generateNativeExplanation generates explanation in user's native language
-func (gd *GrammarDetector) generateNativeExplanation(englishExplanation string, nativeLanguage string) string {
-	// Quick translations for common explanations
-	translations := map[string]map[string]string{
-		"Hindi": {
-			"Use 'have' with 'I', not 'has'": "'I' के साथ 'have' का उपयोग करें, 'has' नहीं",
-			"Use 'has' with 'he/she/it', not 'have'": "'he/she/it' के साथ 'has' का उपयोग करें, 'have' नहीं",
-			"Use 'are' with 'they', not 'is'": "'they' के साथ 'are' का उपयोग करें, 'is' नहीं",
-			"Use past tense 'went' with 'yesterday'": "'yesterday' के साथ भूतकाल 'went' का उपयोग करें",
-		},
-		"Tamil": {
-			"Use 'have' with 'I', not 'has'": "'I' உடன் 'have' பயன்படுத்தவும், 'has' அல்ல",
-			"Use 'has' with 'he/she/it', not 'have'": "'he/she/it' உடன் 'has' பயன்படுத்தவும், 'have' அல்ல",
-		},
-		"Telugu": {
-			"Use 'have' with 'I', not 'has'": "'I' తో 'have' ఉపయోగించండి, 'has' కాదు",
-			"Use 'has' with 'he/she/it', not 'have'": "'he/she/it' తో 'has' ఉపయోగించండి, 'have' కాదు",
-		},
+// locateSpan finds the first whole-word occurrence of words in text that
+// no earlier finding claimed, ignoring case if needed. It returns -1 if
+// there is none.
+func locateSpan(text, words string, taken []bool) int {
+	if words == "" {
+		return -1
+	}
+
+	if start := findUnclaimed(text, words, taken); start >= 0 {
+		return start
+	}
+	lower, lowerWords := strings.ToLower(text), strings.ToLower(words)
+	if len(lower) == len(text) && len(lowerWords) == len(words) {
+		return findUnclaimed(lower, lowerWords, taken)
+	}
+	return -1
+}
+
+// findUnclaimed returns the first whole-word occurrence of words in text
+// outside the taken bytes
+func findUnclaimed(text, words string, taken []bool) int {
+	for from := 0; from < len(text); {
+		i := strings.Index(text[from:], words)
+		if i < 0 {
+			break
+		}
+		start, end := from+i, from+i+len(words)
+		if !isWordByte(text, start-1) && !isWordByte(text, end) && !slices.Contains(taken[start:end], true) {
+			return start
+		}
+		from = start + 1
 	}
+	return -1
+}
 
-	// Check if translation exists
-	if langMap, ok := translations[nativeLanguage]; ok {
-		if translation, ok := langMap[englishExplanation]; ok {
-			return translation
+// isWordByte reports whether text[i] exists and is part of a word
+func isWordByte(text string, i int) bool {
+	if i < 0 || i >= len(text) {
+		return false
+	}
+	c := text[i]
+	return c == '\'' || c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+// llmConfidence clamps the confidence the LLM reports for a finding
+func llmConfidence(reported float64) float64 {
+	if reported <= 0 {
+		return defaultLLMConfidence
+	}
+	return min(max(reported, minLLMConfidence), maxLLMConfidence)
+}
+
+// translateExplanation returns the cached translation of an explanation,
+// asking the LLM and caching its answer on a miss. Romanized explanations
+// in a script translit covers are transliterated from the native one
+// rather than asked for.
+func (gd *GrammarDetector) translateExplanation(ctx context.Context, englishExplanation, ruleID, nativeLanguage, style string) string {
+	if style == StyleRomanized && translit.Covers(nativeLanguage) {
+		native := gd.translateExplanation(ctx, englishExplanation, ruleID, nativeLanguage, StyleNative)
+		if romanized, ok := romanize(native); ok {
+			return romanized
 		}
 	}
 
-	// Fallback: use LLM for translation if not in quick translations
-	if gd.llmRouter != nil {
-		prompt := fmt.Sprintf(`Translate this English explanation to %s (keep it concise, under 20 words):
-"%s"
+	if gd.translations == nil {
+		return gd.generateNativeExplanation(ctx, englishExplanation, nativeLanguage, style)
+	}
+
+	key := TranslationKey{English: englishExplanation, Language: nativeLanguage, Style: style}
+	if cached, ok := gd.translations.Get(ctx, key); ok {
+		return cached.Translation
+	}
+
+	translation := gd.generateNativeExplanation(ctx, englishExplanation, nativeLanguage, style)
+	if translation != englishExplanation {
+		gd.translations.Put(key, ruleID, translation)
+	}
+	return translation
+}
+
+// generateNativeExplanation translates an explanation the message catalogue
+// does not have, such as an LLM finding's, into the user's native language
+// in style. It returns the English once ctx is done.
+func (gd *GrammarDetector) generateNativeExplanation(ctx context.Context, englishExplanation string, nativeLanguage, style string) string {
+	if gd.llmRouter != nil && ctx.Err() == nil {
+		language := promptLanguage(nativeLanguage)
+		instruction := fmt.Sprintf("Translate this English explanation to %s", language)
+		switch style {
+		case StyleRomanized:
+			instruction = fmt.Sprintf("Translate this English explanation to %s, written in the Latin alphabet the way people type it in chats", language)
+		case StyleCodeMixed:
+			instruction = fmt.Sprintf(`Explain this in %s mixed with English, the way bilingual speakers talk, written in the Latin alphabet.
+Keep grammar terms and the English words being corrected in English, e.g. in Hindi: "Subject singular hai toh verb bhi singular hoga"`, language)
+		}
+
+		prompt := fmt.Sprintf(`%s (keep it concise, under 20 words):
+%s
+
+%s
 
-Only provide the translation, no other text.`, nativeLanguage, englishExplanation)
+Only provide the translation, no other text.`, instruction, quoteUserText(englishExplanation), userTextInstruction)
 
-		translation, err := gd.llmRouter.Generate(prompt)
+		translation, err := gd.llmRouter.GenerateContext(ctx, prompt)
 		if err == nil && translation != "" {
 			return strings.TrimSpace(translation)
 		}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/rules"
+)
+
+const (
+	// minScoredWords is the fewest words a score is normalised by, so one
+	// slip in a short answer doesn't sink the score
+	minScoredWords = 50
+
+	// pointsPerWeightedError is how many points each weighted error per
+	// 100 words takes off the score. A critical agreement error in every
+	// 100 words costs about 24 points.
+	pointsPerWeightedError = 5.0
+
+	// fixedErrorWeight is the share of its weight an error keeps once the
+	// user said the corrected sentence without it
+	fixedErrorWeight = 0.25
+)
+
+// severityWeights are how much an error of each severity counts
+var severityWeights = map[rules.Severity]float64{
+	rules.SeverityCritical: 4,
+	rules.SeverityMajor:    3,
+	rules.SeverityMinor:    1.5,
+	rules.SeverityStyle:    0.5,
+}
+
+// categoryWeights scale the severity weight by category. Errors that change
+// the meaning weigh more; Indianisms and redundancy are understood anyway,
+// and spelling in a transcript is mostly the STT's. Other categories count 1.
+var categoryWeights = map[string]float64{
+	"agreement":   1.2,
+	"tense":       1.2,
+	"negation":    1.2,
+	"word-order":  1.1,
+	"article":     0.8,
+	"preposition": 0.8,
+	"word-choice": 0.8,
+	"indianism":   0.6,
+	"redundancy":  0.6,
+	"spelling":    0.5,
+}
+
+// GrammarScore rates the grammar of a session from 0 to 100
+type GrammarScore struct {
+	Score     float64        `json:"score"` // Rounded to two decimals, as sessions.grammar_score holds it
+	Breakdown ScoreBreakdown `json:"breakdown"`
+}
+
+// ScoreBreakdown shows how a grammar score was reached
+type ScoreBreakdown struct {
+	WordCount            int             `json:"word_count"`
+	ScoredWords          int             `json:"scored_words"` // Words the errors are normalised by, at least 50
+	ErrorCount           int             `json:"error_count"`
+	FixedCount           int             `json:"fixed_count"` // Errors the user fixed when repeating the corrected sentence
+	WeightedErrors       float64         `json:"weighted_errors"`
+	WeightedErrorsPer100 float64         `json:"weighted_errors_per_100_words"`
+	Deduction            float64         `json:"deduction"`  // Points taken off 100
+	Categories           []CategoryScore `json:"categories"` // Largest deduction first
+}
+
+// CategoryScore is what the errors of one category cost
+type CategoryScore struct {
+	Category       string         `json:"category"`
+	Errors         int            `json:"errors"`
+	Fixed          int            `json:"fixed"`
+	Severities     map[string]int `json:"severities"` // Errors by severity
+	WeightedErrors float64        `json:"weighted_errors"`
+	Deduction      float64        `json:"deduction"`
+}
+
+// scoredFinding is an error shown to the user during a session
+type scoredFinding struct {
+	severity    rules.Severity
+	category    string
+	occurrences int // Times it was shown, in different utterances
+	fixed       int // Times the user fixed it when repeating
+}
+
+// expectedFix is an error the user was asked to correct by repeating the
+// corrected sentence
+type expectedFix struct {
+	key         string   // The finding's key in the session
+	utteranceID string   // The utterance the error was in
+	corrected   []string // The corrected sentence
+	matched     []string // The words that were wrong
+	replacement []string // The words to say instead
+}
+
+// newExpectedFix returns what repeating the correction of a finding in an
+// utterance should sound like
+func newExpectedFix(utteranceID string, errorResult *ErrorResult) expectedFix {
+	return expectedFix{
+		key:         findingKey(errorResult),
+		utteranceID: utteranceID,
+		corrected:   correctionWords(errorResult.Corrected),
+		matched:     correctionWords(errorResult.Matched),
+		replacement: correctionWords(errorResult.Replacement),
+	}
+}
+
+// fixedBy reports whether heard repeats the corrected sentence, saying the
+// replacement and not the error. Attempts too unlike the sentence are taken
+// as the speaker moving on.
+func (f expectedFix) fixedBy(heard []string) bool {
+	if len(f.corrected) == 0 || len(f.matched) == 0 || wordDistance(f.corrected, heard)*2 > len(f.corrected) {
+		return false
+	}
+	if len(f.replacement) > 0 && !containsWords(heard, f.replacement) {
+		return false
+	}
+	// The error can be part of its fix, e.g. "is" of "this is"
+	return containsWords(f.replacement, f.matched) || !containsWords(heard, f.matched)
+}
+
+// containsWords reports whether phrase occurs in words
+func containsWords(words, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		if slices.Equal(words[i:i+len(phrase)], phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// findingKey identifies an error within a session: the same rule flagging
+// the same words is the same error
+func findingKey(errorResult *ErrorResult) string {
+	return errorResult.RuleID + ":" + errorResult.Matched
+}
+
+// errorWeight is how much one occurrence of an error counts
+func errorWeight(severity rules.Severity, category string) float64 {
+	weight, ok := severityWeights[severity]
+	if !ok {
+		weight = severityWeights[rules.SeverityMajor]
+	}
+	if scale, ok := categoryWeights[category]; ok {
+		weight *= scale
+	}
+	return weight
+}
+
+// scoreGrammar rates findings over wordCount words of speech. Each error
+// counts by its severity and category, fixed ones at fixedErrorWeight, per
+// 100 words; the score drops pointsPerWeightedError for each and stays in
+// 0-100. It returns nil if nothing was said.
+func scoreGrammar(findings map[string]*scoredFinding, wordCount int) *GrammarScore {
+	if wordCount == 0 {
+		return nil
+	}
+
+	breakdown := ScoreBreakdown{
+		WordCount:   wordCount,
+		ScoredWords: max(wordCount, minScoredWords),
+		Categories:  make([]CategoryScore, 0),
+	}
+	per100 := 100 / float64(breakdown.ScoredWords)
+
+	categories := make(map[string]*CategoryScore)
+	for _, finding := range findings {
+		category, ok := categories[finding.category]
+		if !ok {
+			category = &CategoryScore{Category: finding.category, Severities: make(map[string]int)}
+			categories[finding.category] = category
+		}
+
+		weight := errorWeight(finding.severity, finding.category)
+		fixed := min(finding.fixed, finding.occurrences)
+		category.Errors += finding.occurrences
+		category.Fixed += fixed
+		category.Severities[string(finding.severity)] += finding.occurrences
+		category.WeightedErrors += weight * (float64(finding.occurrences-fixed) + fixedErrorWeight*float64(fixed))
+	}
+
+	for _, category := range categories {
+		category.Deduction = category.WeightedErrors * per100 * pointsPerWeightedError
+		breakdown.ErrorCount += category.Errors
+		breakdown.FixedCount += category.Fixed
+		breakdown.WeightedErrors += category.WeightedErrors
+		breakdown.Deduction += category.Deduction
+		category.WeightedErrors = round2(category.WeightedErrors)
+		category.Deduction = round2(category.Deduction)
+		breakdown.Categories = append(breakdown.Categories, *category)
+	}
+	sort.Slice(breakdown.Categories, func(i, j int) bool {
+		a, b := breakdown.Categories[i], breakdown.Categories[j]
+		if a.Deduction != b.Deduction {
+			return a.Deduction > b.Deduction
+		}
+		return a.Category < b.Category
+	})
+
+	breakdown.WeightedErrorsPer100 = round2(breakdown.WeightedErrors * per100)
+	breakdown.WeightedErrors = round2(breakdown.WeightedErrors)
+	score := round2(max(0, 100-breakdown.Deduction))
+	breakdown.Deduction = round2(breakdown.Deduction)
+	return &GrammarScore{Score: score, Breakdown: breakdown}
+}
+
+// round2 rounds to two decimals
+func round2(x float64) float64 {
+	return math.Round(x*100) / 100
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/vocabulary"
+)
+
+// LexicalAnalyzer finds the basic words speakers overuse, such as "very
+// good", "nice" and "a lot of", and suggests stronger ones graded by CEFR
+// level. Like fluency, vocabulary never interrupts the speaker: sessions
+// count the words and get one tip per word once it is overused.
+type LexicalAnalyzer struct {
+	words *vocabulary.List
+}
+
+// VocabularyTip suggests replacements for a basic word said too often
+type VocabularyTip struct {
+	Word     string               `json:"word"`  // The basic word, e.g. "very good"
+	Count    int                  `json:"count"` // Times it was said in the session so far
+	Said     string               `json:"said"`  // How it was said last, e.g. "lots of" for "a lot of"
+	Synonyms []vocabulary.Synonym `json:"synonyms"`
+	Example  string               `json:"example,omitempty"`
+}
+
+// VocabularySummary is the vocabulary section of a session summary
+type VocabularySummary struct {
+	BasicWordCount int              `json:"basic_word_count"` // Basic words said, counting repeats
+	BasicWords     []VocabularyWord `json:"basic_words"`      // Most said first
+	TipsSent       int              `json:"tips_sent"`
+}
+
+// VocabularyWord is a basic word said in a session
+type VocabularyWord struct {
+	Word     string               `json:"word"`
+	Count    int                  `json:"count"`
+	Overused bool                 `json:"overused"` // Said at least as often as a tip needs
+	Tipped   bool                 `json:"tipped"`
+	Synonyms []vocabulary.Synonym `json:"synonyms"`
+}
+
+// vocabularyUsage counts the basic words of a session
+type vocabularyUsage struct {
+	counts map[*vocabulary.Entry]int
+	tipped map[*vocabulary.Entry]bool
+}
+
+// NewLexicalAnalyzer creates a lexical analyzer with the built-in word list
+func NewLexicalAnalyzer() *LexicalAnalyzer {
+	return &LexicalAnalyzer{
+		words: vocabulary.Default(),
+	}
+}
+
+// newVocabularyUsage creates empty counts for a session
+func newVocabularyUsage() *vocabularyUsage {
+	return &vocabularyUsage{
+		counts: make(map[*vocabulary.Entry]int),
+		tipped: make(map[*vocabulary.Entry]bool),
+	}
+}
+
+// track counts the basic words in a final transcript and returns tips for
+// the ones that have just become overused. A word gets at most one tip.
+func (la *LexicalAnalyzer) track(usage *vocabularyUsage, text string) []VocabularyTip {
+	tips := make([]VocabularyTip, 0)
+	for _, use := range la.words.Find(text) {
+		entry := use.Entry
+		usage.counts[entry]++
+		if usage.tipped[entry] || usage.counts[entry] < entry.MinUses {
+			continue
+		}
+
+		usage.tipped[entry] = true
+		tips = append(tips, VocabularyTip{
+			Word:     entry.Phrase,
+			Count:    usage.counts[entry],
+			Said:     use.Text,
+			Synonyms: entry.Synonyms,
+			Example:  entry.Example,
+		})
+	}
+	return tips
+}
+
+// summary lists the basic words of a session
+func (u *vocabularyUsage) summary() VocabularySummary {
+	summary := VocabularySummary{BasicWords: make([]VocabularyWord, 0, len(u.counts))}
+	for entry, count := range u.counts {
+		summary.BasicWordCount += count
+		if u.tipped[entry] {
+			summary.TipsSent++
+		}
+		summary.BasicWords = append(summary.BasicWords, VocabularyWord{
+			Word:     entry.Phrase,
+			Count:    count,
+			Overused: count >= entry.MinUses,
+			Tipped:   u.tipped[entry],
+			Synonyms: entry.Synonyms,
+		})
+	}
+
+	sort.Slice(summary.BasicWords, func(i, j int) bool {
+		a, b := summary.BasicWords[i], summary.BasicWords[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Word < b.Word
+	})
+	return summary
+}
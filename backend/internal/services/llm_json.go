@@ -0,0 +1,269 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrUnparseableResponse is returned when an LLM's answer is not the JSON
+// it was asked for, even after a repair attempt
+var ErrUnparseableResponse = errors.New("unparseable LLM response")
+
+// Schema describes the JSON an LLM must answer with. It is a small subset of
+// JSON Schema: types, object properties, required fields, array items and
+// number bounds.
+type Schema struct {
+	Type       string             `json:"type"` // "object", "array", "string", "number" or "boolean"
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	Minimum    *float64           `json:"minimum,omitempty"`
+	Maximum    *float64           `json:"maximum,omitempty"`
+}
+
+// Validate checks a value decoded from JSON into interface{} against the
+// schema. The error names the path of the first offending field, e.g.
+// "errors[1].confidence".
+func (s *Schema) Validate(value interface{}) error {
+	return s.validate("", value)
+}
+
+func (s *Schema) validate(path string, value interface{}) error {
+	name := path
+	if name == "" {
+		name = "response"
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected an object, got %s", name, jsonKind(value))
+		}
+		for _, field := range s.Required {
+			if _, ok := object[field]; !ok {
+				return fmt.Errorf("%s: missing required field %q", name, field)
+			}
+		}
+		fields := make([]string, 0, len(s.Properties))
+		for field := range s.Properties {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			if fieldValue, ok := object[field]; ok {
+				if err := s.Properties[field].validate(joinPath(path, field), fieldValue); err != nil {
+					return err
+				}
+			}
+		}
+
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected an array, got %s", name, jsonKind(value))
+		}
+		if s.Items != nil {
+			for i, item := range array {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: expected a string, got %s", name, jsonKind(value))
+		}
+
+	case "number":
+		number, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s: expected a number, got %s", name, jsonKind(value))
+		}
+		if s.Minimum != nil && number < *s.Minimum {
+			return fmt.Errorf("%s: %v is below the minimum %v", name, number, *s.Minimum)
+		}
+		if s.Maximum != nil && number > *s.Maximum {
+			return fmt.Errorf("%s: %v is above the maximum %v", name, number, *s.Maximum)
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected a boolean, got %s", name, jsonKind(value))
+		}
+	}
+	return nil
+}
+
+// joinPath appends a field name to a path
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// jsonKind names the JSON type of a decoded value for error messages
+func jsonKind(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// decodeStructured extracts the JSON from an LLM response, checks it
+// against schema and decodes it into out
+func decodeStructured(response string, schema *Schema, out interface{}) error {
+	candidate, err := ExtractJSON(response)
+	if err != nil {
+		return err
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(candidate), &value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if schema != nil {
+		if err := schema.Validate(value); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal([]byte(candidate), out)
+}
+
+// ExtractJSON returns the JSON object or array in an LLM response. Models
+// wrap it in markdown code fences or prose despite instructions, and leave
+// trailing commas; the first complete object or array is taken, preferring
+// one inside a fence, and trailing commas are dropped.
+func ExtractJSON(response string) (string, error) {
+	text := strings.TrimSpace(response)
+	if fenced, ok := fencedBlock(text); ok {
+		text = fenced
+	}
+
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return "", errors.New("no JSON object in response")
+	}
+
+	end, err := matchingBracket(text, start)
+	if err != nil {
+		return "", err
+	}
+	return removeTrailingCommas(text[start : end+1]), nil
+}
+
+// fencedBlock returns the contents of the first markdown code fence in text
+// that holds an object or array
+func fencedBlock(text string) (string, bool) {
+	for rest := text; ; {
+		open := strings.Index(rest, "```")
+		if open < 0 {
+			return "", false
+		}
+		body := rest[open+3:]
+
+		// Skip the language tag, e.g. ```json
+		if newline := strings.IndexByte(body, '\n'); newline >= 0 && !strings.ContainsAny(body[:newline], "{[") {
+			body = body[newline+1:]
+		}
+
+		closing := strings.Index(body, "```")
+		if closing < 0 {
+			// An unterminated fence still holds the JSON
+			closing = len(body)
+		}
+		if block := strings.TrimSpace(body[:closing]); strings.ContainsAny(block, "{[") {
+			return block, true
+		}
+		if closing == len(body) {
+			return "", false
+		}
+		rest = body[closing+3:]
+	}
+}
+
+// matchingBracket returns the index of the bracket closing the one at
+// start, skipping brackets inside strings
+func matchingBracket(text string, start int) (int, error) {
+	depth := 0
+	inString, escaped := false, false
+
+	for i := start; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, errors.New("unterminated JSON in response")
+}
+
+// removeTrailingCommas drops commas directly before a closing bracket,
+// outside strings
+func removeTrailingCommas(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	inString, escaped := false, false
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case !inString && c == ',':
+			next := i + 1
+			for next < len(text) && strings.IndexByte(" \t\r\n", text[next]) >= 0 {
+				next++
+			}
+			if next < len(text) && (text[next] == '}' || text[next] == ']') {
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// repairPrompt asks the model to fix an answer that failed to parse
+func repairPrompt(prompt, response string, parseErr error) string {
+	return fmt.Sprintf(`%s
+
+Your previous answer could not be used:
+%s
+
+Error: %v
+
+Answer again with only the JSON object, no markdown and no other text.`, prompt, response, parseErr)
+}
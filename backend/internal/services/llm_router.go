@@ -2,11 +2,13 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -16,6 +18,22 @@ type LLMRouter struct {
 	openAIAPIKey string
 	geminiAPIKey string
 	httpClient   *http.Client
+
+	stats   map[string]*LLMProviderStats // By provider name
+	statsMu sync.Mutex
+}
+
+// LLMProviderStats counts a provider's answers to requests for JSON
+type LLMProviderStats struct {
+	Structured  int `json:"structured"`  // Answers to requests for JSON, repairs included
+	Unparseable int `json:"unparseable"` // Answers that were not the JSON asked for
+	Repaired    int `json:"repaired"`    // Unparseable answers fixed by the repair retry
+}
+
+// llmProvider is one LLM API the router can call
+type llmProvider struct {
+	name string
+	call func(ctx context.Context, prompt string, jsonMode bool) (string, error)
 }
 
 // NewLLMRouter creates a new LLM router
@@ -27,43 +45,155 @@ func NewLLMRouter() *LLMRouter {
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		stats: make(map[string]*LLMProviderStats),
 	}
 }
 
 // Generate generates text using LLM with fallback
 func (lr *LLMRouter) Generate(prompt string) (string, error) {
+	return lr.GenerateContext(context.Background(), prompt)
+}
+
+// GenerateContext is Generate with a context. Once ctx is done the request
+// in flight is abandoned and no further provider is tried.
+func (lr *LLMRouter) GenerateContext(ctx context.Context, prompt string) (string, error) {
 	// Try Groq first (fastest)
 	if lr.groqAPIKey != "" {
-		response, err := lr.callGroq(prompt)
+		response, err := lr.callGroq(ctx, prompt, false)
 		if err == nil {
 			return response, nil
 		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 		fmt.Printf("Groq failed: %v, falling back to OpenAI\n", err)
 	}
 
 	// Fallback to OpenAI
 	if lr.openAIAPIKey != "" {
-		response, err := lr.callOpenAI(prompt)
+		response, err := lr.callOpenAI(ctx, prompt, false)
 		if err == nil {
 			return response, nil
 		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 		fmt.Printf("OpenAI failed: %v, falling back to Gemini\n", err)
 	}
 
 	// Fallback to Gemini
 	if lr.geminiAPIKey != "" {
-		response, err := lr.callGemini(prompt)
+		response, err := lr.callGemini(ctx, prompt, false)
 		if err == nil {
 			return response, nil
 		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 		fmt.Printf("Gemini failed: %v\n", err)
 	}
 
 	return "", fmt.Errorf("all LLM providers failed")
 }
 
+// GenerateStructured asks for JSON matching schema and decodes it into out,
+// falling back between providers like GenerateContext. Providers with a JSON
+// mode are asked to use it. JSON wrapped in code fences or prose is
+// extracted; an answer that still does not parse or fit the schema is sent
+// back once with the error for the model to fix. If the fix fails too the
+// next provider is asked; when none answers usably the last error is
+// returned, wrapping ErrUnparseableResponse if that provider's fix did not
+// parse.
+func (lr *LLMRouter) GenerateStructured(ctx context.Context, prompt string, schema *Schema, out interface{}) error {
+	lastErr := fmt.Errorf("all LLM providers failed")
+	for _, provider := range lr.providers() {
+		response, err := provider.call(ctx, prompt, true)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Printf("%s failed: %v, falling back\n", provider.name, err)
+			lastErr = err
+			continue
+		}
+
+		parseErr := decodeStructured(response, schema, out)
+		lr.recordAnswer(provider.name, parseErr == nil, false)
+		if parseErr == nil {
+			return nil
+		}
+		fmt.Printf("%s returned unparseable JSON: %v, asking for a repair\n", provider.name, parseErr)
+
+		repaired, err := provider.call(ctx, repairPrompt(prompt, response, parseErr), true)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			lastErr = fmt.Errorf("%s repair failed: %w", provider.name, err)
+			fmt.Printf("%v, falling back\n", lastErr)
+			continue
+		}
+
+		parseErr = decodeStructured(repaired, schema, out)
+		lr.recordAnswer(provider.name, parseErr == nil, true)
+		if parseErr == nil {
+			return nil
+		}
+		lastErr = fmt.Errorf("%w from %s: %v", ErrUnparseableResponse, provider.name, parseErr)
+		fmt.Printf("%v, falling back\n", lastErr)
+	}
+
+	return lastErr
+}
+
+// Stats returns a copy of the per-provider counts of structured answers
+func (lr *LLMRouter) Stats() map[string]LLMProviderStats {
+	lr.statsMu.Lock()
+	defer lr.statsMu.Unlock()
+
+	stats := make(map[string]LLMProviderStats, len(lr.stats))
+	for name, providerStats := range lr.stats {
+		stats[name] = *providerStats
+	}
+	return stats
+}
+
+// recordAnswer counts a structured answer of a provider
+func (lr *LLMRouter) recordAnswer(provider string, parsed, repair bool) {
+	lr.statsMu.Lock()
+	defer lr.statsMu.Unlock()
+
+	stats, ok := lr.stats[provider]
+	if !ok {
+		stats = &LLMProviderStats{}
+		lr.stats[provider] = stats
+	}
+	stats.Structured++
+	switch {
+	case !parsed:
+		stats.Unparseable++
+	case repair:
+		stats.Repaired++
+	}
+}
+
+// providers returns the configured providers in fallback order
+func (lr *LLMRouter) providers() []llmProvider {
+	providers := make([]llmProvider, 0, 3)
+	if lr.groqAPIKey != "" {
+		providers = append(providers, llmProvider{name: "groq", call: lr.callGroq})
+	}
+	if lr.openAIAPIKey != "" {
+		providers = append(providers, llmProvider{name: "openai", call: lr.callOpenAI})
+	}
+	if lr.geminiAPIKey != "" {
+		providers = append(providers, llmProvider{name: "gemini", call: lr.callGemini})
+	}
+	return providers
+}
+
 // callGroq calls Groq API
-func (lr *LLMRouter) callGroq(prompt string) (string, error) {
+func (lr *LLMRouter) callGroq(ctx context.Context, prompt string, jsonMode bool) (string, error) {
 	url := "https://api.groq.com/openai/v1/chat/completions"
 
 	requestBody := map[string]interface{}{
@@ -77,12 +207,15 @@ func (lr *LLMRouter) callGroq(prompt string) (string, error) {
 		"temperature": 0.3,
 		"max_tokens":  500,
 	}
+	if jsonMode {
+		requestBody["response_format"] = map[string]string{"type": "json_object"}
+	}
 
-	return lr.makeRequest(url, lr.groqAPIKey, requestBody, "groq")
+	return lr.makeRequest(ctx, url, lr.groqAPIKey, requestBody, "groq")
 }
 
 // callOpenAI calls OpenAI API
-func (lr *LLMRouter) callOpenAI(prompt string) (string, error) {
+func (lr *LLMRouter) callOpenAI(ctx context.Context, prompt string, jsonMode bool) (string, error) {
 	url := "https://api.openai.com/v1/chat/completions"
 
 	requestBody := map[string]interface{}{
@@ -96,12 +229,16 @@ func (lr *LLMRouter) callOpenAI(prompt string) (string, error) {
 		"temperature": 0.3,
 		"max_tokens":  500,
 	}
+	if jsonMode {
+		requestBody["response_format"] = map[string]string{"type": "json_object"}
+	}
 
-	return lr.makeRequest(url, lr.openAIAPIKey, requestBody, "openai")
+	return lr.makeRequest(ctx, url, lr.openAIAPIKey, requestBody, "openai")
 }
 
-// callGemini calls Google Gemini API
-func (lr *LLMRouter) callGemini(prompt string) (string, error) {
+// callGemini calls Google Gemini API. gemini-pro has no JSON mode, so
+// jsonMode is ignored and the answer is extracted from the text.
+func (lr *LLMRouter) callGemini(ctx context.Context, prompt string, jsonMode bool) (string, error) {
 	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/gemini-pro:generateContent?key=%s", lr.geminiAPIKey)
 
 	requestBody := map[string]interface{}{
@@ -125,7 +262,7 @@ func (lr *LLMRouter) callGemini(prompt string) (string, error) {
 		return "", err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", err
 	}
@@ -165,13 +302,13 @@ func (lr *LLMRouter) callGemini(prompt string) (string, error) {
 }
 
 // makeRequest makes HTTP request to LLM API
-func (lr *LLMRouter) makeRequest(url string, apiKey string, requestBody map[string]interface{}, provider string) (string, error) {
+func (lr *LLMRouter) makeRequest(ctx context.Context, url string, apiKey string, requestBody map[string]interface{}, provider string) (string, error) {
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
 		return "", err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", err
 	}
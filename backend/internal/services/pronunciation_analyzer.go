@@ -0,0 +1,360 @@
+package services
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// lowWordConfidence is the STT confidence below which a word was
+	// probably not pronounced clearly
+	lowWordConfidence = 0.6
+
+	// minLowConfidence is how many unclear occurrences put a word on the
+	// practise list, if they are at least minLowConfidenceShare of the times
+	// it was said. One misrecognition while repeating a correction does.
+	minLowConfidence      = 2
+	minLowConfidenceShare = 0.5
+
+	// maxPracticeOffsets is how many audio offsets are kept per word
+	maxPracticeOffsets = 5
+)
+
+// Reasons a word is on the practise list
+const (
+	HintLowConfidence = "low_confidence" // The STT was repeatedly unsure of the word
+	HintMisrecognised = "misrecognised"  // The STT heard another word where a repeated correction had it
+)
+
+// PronunciationAnalyzer finds words a speaker may be mispronouncing from
+// the per-word confidences of the STT, and from the words it hears instead
+// of the expected ones when the speaker repeats a corrected sentence. Like
+// fluency, pronunciation never interrupts the speaker.
+type PronunciationAnalyzer struct {
+	sessions map[string]*PronunciationSession
+	mu       sync.RWMutex
+}
+
+// PronunciationSession holds the words heard in one session
+type PronunciationSession struct {
+	sessionID string
+	words     map[string]*PracticeWord // By normalised word
+	expected  *expectedRepeat          // Sentence the speaker was asked to repeat
+	mu        sync.Mutex
+}
+
+// expectedRepeat is a corrected sentence the next utterance should repeat
+type expectedRepeat struct {
+	utteranceID string // The utterance the finding was in
+	words       []string
+	corrected   map[string]bool // Words of the correction, which are grammar rather than pronunciation
+}
+
+// PracticeWord is a word on a session's practise list
+type PracticeWord struct {
+	Word              string        `json:"word"`
+	Reason            string        `json:"reason"` // HintLowConfidence or HintMisrecognised
+	Occurrences       int           `json:"occurrences"`
+	LowConfidence     int           `json:"low_confidence"`
+	Misrecognised     int           `json:"misrecognised"`
+	Heard             []string      `json:"heard,omitempty"` // What the STT heard when it misrecognised the word
+	AverageConfidence float64       `json:"average_confidence"`
+	Offsets           []AudioOffset `json:"offsets"` // Where the unclear occurrences are in the audio
+
+	confidenceSum float64
+	scored        int  // Occurrences that had a confidence
+	listed        bool // Already reported by AnalyzeUtterance
+}
+
+// AudioOffset locates a word in the audio of an utterance
+type AudioOffset struct {
+	UtteranceID string  `json:"utterance_id"`
+	Start       float64 `json:"start"` // Seconds from the start of the utterance's audio
+	End         float64 `json:"end"`
+}
+
+// NewPronunciationAnalyzer creates a new pronunciation analyzer
+func NewPronunciationAnalyzer() *PronunciationAnalyzer {
+	return &PronunciationAnalyzer{
+		sessions: make(map[string]*PronunciationSession),
+	}
+}
+
+// StartSession initializes pronunciation tracking for a session
+func (pa *PronunciationAnalyzer) StartSession(sessionID string) {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+
+	pa.sessions[sessionID] = &PronunciationSession{
+		sessionID: sessionID,
+		words:     make(map[string]*PracticeWord),
+	}
+}
+
+// EndSession removes a session from memory
+func (pa *PronunciationAnalyzer) EndSession(sessionID string) {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+
+	delete(pa.sessions, sessionID)
+}
+
+// session returns the session, creating it if needed
+func (pa *PronunciationAnalyzer) session(sessionID string) *PronunciationSession {
+	pa.mu.RLock()
+	session, exists := pa.sessions[sessionID]
+	pa.mu.RUnlock()
+
+	if !exists {
+		pa.StartSession(sessionID)
+		pa.mu.RLock()
+		session = pa.sessions[sessionID]
+		pa.mu.RUnlock()
+	}
+	return session
+}
+
+// ExpectRepeat tells the analyzer the speaker was asked to repeat the
+// corrected sentence of a finding in an utterance. The next utterance is
+// compared with it.
+func (pa *PronunciationAnalyzer) ExpectRepeat(sessionID, utteranceID string, errorResult *ErrorResult) {
+	corrected := make(map[string]bool)
+	for _, word := range correctionWords(errorResult.Matched + " " + errorResult.Replacement) {
+		corrected[word] = true
+	}
+
+	session := pa.session(sessionID)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	session.expected = &expectedRepeat{
+		utteranceID: utteranceID,
+		words:       correctionWords(errorResult.Corrected),
+		corrected:   corrected,
+	}
+}
+
+// AnalyzeUtterance records the words of a final transcript and returns the
+// words that have just been put on the practise list. words are the STT's
+// per-word timings and confidences; without them only the text is compared
+// with an expected repeat.
+func (pa *PronunciationAnalyzer) AnalyzeUtterance(sessionID, utteranceID, text string, words []TranscriptWord) []PracticeWord {
+	if len(words) == 0 {
+		// A confidence of -1 marks words the STT gave no data for
+		for _, word := range correctionWords(text) {
+			words = append(words, TranscriptWord{Word: word, Confidence: -1})
+		}
+	}
+
+	session := pa.session(sessionID)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	heard := make([]string, len(words))
+	for i, word := range words {
+		heard[i] = normaliseWord(word.Word)
+		if !practisable(heard[i]) {
+			continue
+		}
+
+		practice := session.word(heard[i])
+		practice.Occurrences++
+		if word.Confidence < 0 {
+			continue
+		}
+		practice.confidenceSum += word.Confidence
+		practice.scored++
+		if word.Confidence < lowWordConfidence {
+			practice.LowConfidence++
+			practice.addOffset(utteranceID, word)
+		}
+	}
+
+	// The final transcript of an utterance interrupted early is the
+	// mistake, not the repeat
+	if expected := session.expected; expected != nil && expected.utteranceID != utteranceID {
+		session.expected = nil
+		session.compareRepeat(expected, heard, words, utteranceID)
+	}
+
+	added := make([]PracticeWord, 0)
+	for _, practice := range session.words {
+		if !practice.listed && practice.needsPractice() {
+			practice.listed = true
+			added = append(added, practice.snapshot())
+		}
+	}
+	sortPracticeWords(added)
+	return added
+}
+
+// compareRepeat aligns an attempt to repeat a corrected sentence with it
+// and counts the expected words the STT heard as similar-sounding other
+// words. Attempts too unlike the sentence are taken as the speaker moving
+// on. Caller must hold s.mu.
+func (s *PronunciationSession) compareRepeat(expected *expectedRepeat, heard []string, words []TranscriptWord, utteranceID string) {
+	if len(expected.words) == 0 || wordDistance(expected.words, heard)*2 > len(expected.words) {
+		return
+	}
+
+	for _, pair := range substitutions(expected.words, heard) {
+		want, got := expected.words[pair[0]], heard[pair[1]]
+		if expected.corrected[want] || !practisable(want) || !soundsAlike(want, got) {
+			continue
+		}
+
+		practice := s.word(want)
+		practice.Misrecognised++
+		if !slices.Contains(practice.Heard, got) {
+			practice.Heard = append(practice.Heard, got)
+		}
+		if words[pair[1]].Confidence >= 0 {
+			practice.addOffset(utteranceID, words[pair[1]])
+		}
+	}
+}
+
+// word returns the practise entry of a word, creating it if needed.
+// Caller must hold s.mu.
+func (s *PronunciationSession) word(word string) *PracticeWord {
+	practice, ok := s.words[word]
+	if !ok {
+		practice = &PracticeWord{Word: word}
+		s.words[word] = practice
+	}
+	return practice
+}
+
+// WordsToPractise returns the words a session's speaker should practise,
+// the most troublesome first, or nil if there is no such session
+func (pa *PronunciationAnalyzer) WordsToPractise(sessionID string) []PracticeWord {
+	pa.mu.RLock()
+	session, exists := pa.sessions[sessionID]
+	pa.mu.RUnlock()
+
+	if !exists {
+		return nil
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	words := make([]PracticeWord, 0)
+	for _, practice := range session.words {
+		if practice.needsPractice() {
+			words = append(words, practice.snapshot())
+		}
+	}
+	sortPracticeWords(words)
+	return words
+}
+
+// needsPractice reports whether a word was misrecognised, or was unclear
+// often enough and in a large enough share of the times it was said
+func (p *PracticeWord) needsPractice() bool {
+	if p.Misrecognised > 0 {
+		return true
+	}
+	return p.LowConfidence >= minLowConfidence && float64(p.LowConfidence) >= minLowConfidenceShare*float64(p.scored)
+}
+
+// addOffset keeps where an unclear occurrence of the word is in the audio
+func (p *PracticeWord) addOffset(utteranceID string, word TranscriptWord) {
+	if len(p.Offsets) < maxPracticeOffsets {
+		p.Offsets = append(p.Offsets, AudioOffset{UtteranceID: utteranceID, Start: word.Start, End: word.End})
+	}
+}
+
+// snapshot copies the word for callers outside the session lock
+func (p *PracticeWord) snapshot() PracticeWord {
+	word := *p
+	word.Reason = HintLowConfidence
+	if p.Misrecognised > 0 {
+		word.Reason = HintMisrecognised
+	}
+	if p.scored > 0 {
+		word.AverageConfidence = p.confidenceSum / float64(p.scored)
+	}
+	word.Heard = append([]string(nil), p.Heard...)
+	word.Offsets = append([]AudioOffset{}, p.Offsets...)
+	return word
+}
+
+// sortPracticeWords orders words by how often they were a problem, then
+// alphabetically
+func sortPracticeWords(words []PracticeWord) {
+	sort.Slice(words, func(i, j int) bool {
+		a := words[i].Misrecognised*minLowConfidence + words[i].LowConfidence
+		b := words[j].Misrecognised*minLowConfidence + words[j].LowConfidence
+		if a != b {
+			return a > b
+		}
+		return words[i].Word < words[j].Word
+	})
+}
+
+// normaliseWord lowercases a word and strips the punctuation around it
+func normaliseWord(word string) string {
+	words := correctionWords(word)
+	if len(words) == 0 {
+		return ""
+	}
+	return words[0]
+}
+
+// practisable reports whether a word is worth practising: not a filler or
+// a one-letter word such as "a" or "I"
+func practisable(word string) bool {
+	return utf8.RuneCountInString(word) > 1 && !fillerWords[word]
+}
+
+// soundsAlike reports whether the STT hearing got for want is likely a
+// mispronunciation rather than another word choice: the spellings differ
+// in at most half of the letters
+func soundsAlike(want, got string) bool {
+	distance := wordDistance(strings.Split(want, ""), strings.Split(got, ""))
+	return distance > 0 && distance*2 <= max(utf8.RuneCountInString(want), 2)
+}
+
+// substitutions aligns two word lists with the fewest edits and returns
+// the index pairs of the words that were replaced by another
+func substitutions(a, b []string) [][2]int {
+	// cost[i][j] is the distance between a[i:] and b[j:]
+	cost := make([][]int, len(a)+1)
+	for i := range cost {
+		cost[i] = make([]int, len(b)+1)
+	}
+	for i := len(a); i >= 0; i-- {
+		for j := len(b); j >= 0; j-- {
+			switch {
+			case i == len(a):
+				cost[i][j] = len(b) - j
+			case j == len(b):
+				cost[i][j] = len(a) - i
+			case a[i] == b[j]:
+				cost[i][j] = cost[i+1][j+1]
+			default:
+				cost[i][j] = 1 + min(cost[i+1][j+1], cost[i+1][j], cost[i][j+1])
+			}
+		}
+	}
+
+	pairs := make([][2]int, 0)
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			i, j = i+1, j+1
+		case cost[i][j] == 1+cost[i+1][j+1]:
+			pairs = append(pairs, [2]int{i, j})
+			i, j = i+1, j+1
+		case cost[i][j] == 1+cost[i+1][j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/rules"
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/supabase"
+)
+
+// A rule is only judged once this many users have disputed it, and is
+// demoted or switched off when that share of its findings is disputed
+const (
+	minDisputersToAdjust = 5
+	demoteRate           = 0.2
+	disableRate          = 0.5
+	maxDisputeExamples   = 5 // Disputed texts kept per rule for the report
+)
+
+// Supabase tables holding the feedback, see supabase/migrations
+const (
+	disputesTable     = "rule_disputes"
+	ruleFeedbackTable = "rule_feedback"
+	persistTimeout    = 10 * time.Second
+)
+
+// Errors returned for disputes that can't be counted
+var (
+	ErrUnknownRule = errors.New("unknown rule_id")
+	ErrNotShown    = errors.New("no finding of this rule was shown to the user")
+	ErrNotFlagged  = errors.New("the rule does not flag this text")
+)
+
+// Dispute is a user's report that a finding was not a mistake
+type Dispute struct {
+	UserID      string    `json:"user_id"`
+	SessionID   string    `json:"session_id,omitempty"`
+	UtteranceID string    `json:"utterance_id,omitempty"`
+	RuleID      string    `json:"rule_id"`
+	Text        string    `json:"text"`              // The utterance the finding was in
+	Matched     string    `json:"matched,omitempty"` // The words the finding flagged
+	Comment     string    `json:"comment,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// RuleFeedback is how often a rule's findings were shown and disputed, and
+// what was done about it
+type RuleFeedback struct {
+	RuleID            string    `json:"rule_id"`
+	Shown             int       `json:"shown"`     // Findings sent to users
+	Disputed          int       `json:"disputed"`  // Findings users said were not mistakes
+	Disputers         int       `json:"disputers"` // Different users who disputed findings
+	FalsePositiveRate float64   `json:"false_positive_rate"`
+	Confidence        float64   `json:"confidence"` // Lowered confidence, 0 if unchanged
+	Disabled          bool      `json:"disabled"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	Examples []string `json:"examples,omitempty"` // Recently disputed texts, newest first
+}
+
+// FeedbackService collects disputed findings and demotes the rules users
+// dispute too often. Without a Supabase client the feedback lives in memory.
+// A user can only dispute findings they were shown, one dispute each, and
+// which findings those were is only kept until the server restarts.
+type FeedbackService struct {
+	db        *supabase.Client
+	feedback  map[string]*RuleFeedback
+	undecided map[string]map[string]int  // Findings shown and not disputed, by rule ID and user ID
+	disputers map[string]map[string]bool // Users who disputed each rule
+	mu        sync.Mutex
+}
+
+// NewFeedbackService creates a feedback service; db may be nil
+func NewFeedbackService(db *supabase.Client) *FeedbackService {
+	return &FeedbackService{
+		db:        db,
+		feedback:  make(map[string]*RuleFeedback),
+		undecided: make(map[string]map[string]int),
+		disputers: make(map[string]map[string]bool),
+	}
+}
+
+// Load restores the feedback saved by earlier runs and applies the rule
+// adjustments it calls for
+func (fs *FeedbackService) Load(ctx context.Context) error {
+	if fs.db == nil {
+		return nil
+	}
+
+	var saved []RuleFeedback
+	if err := fs.db.Select(ctx, ruleFeedbackTable, url.Values{"select": {"*"}}, &saved); err != nil {
+		return err
+	}
+	var disputes []Dispute
+	if err := fs.db.Select(ctx, disputesTable, url.Values{"select": {"rule_id,user_id"}}, &disputes); err != nil {
+		return err
+	}
+
+	fs.mu.Lock()
+	for i := range saved {
+		saved[i].Examples = nil
+		fs.feedback[saved[i].RuleID] = &saved[i]
+	}
+	for _, dispute := range disputes {
+		fs.addDisputer(dispute.RuleID, dispute.UserID)
+	}
+	fs.mu.Unlock()
+
+	fs.Adjust(ctx)
+	return nil
+}
+
+// RecordShown counts the findings sent to a user, the denominator of each
+// rule's false-positive rate. userID may be empty for anonymous checks,
+// whose findings can't be disputed.
+func (fs *FeedbackService) RecordShown(userID string, results []*ErrorResult) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	for _, result := range results {
+		fs.ruleFeedback(result.RuleID).Shown++
+		if userID == "" {
+			continue
+		}
+		shown, ok := fs.undecided[result.RuleID]
+		if !ok {
+			shown = make(map[string]int)
+			fs.undecided[result.RuleID] = shown
+		}
+		shown[userID]++
+	}
+}
+
+// Dispute records that a finding was not a mistake. The rule must flag the
+// disputed text and have been shown to the user for a finding they haven't
+// disputed yet. It is saved in the background; only a dispute that can't be
+// counted returns an error.
+func (fs *FeedbackService) Dispute(dispute Dispute) error {
+	if dispute.UserID == "" {
+		return fmt.Errorf("user_id is required")
+	}
+	if dispute.Text == "" {
+		return fmt.Errorf("text is required")
+	}
+	if dispute.RuleID != LLMRuleID {
+		rule := rules.Base().Lookup(dispute.RuleID)
+		if rule == nil {
+			return ErrUnknownRule
+		}
+		if !rule.Flags(dispute.Text) {
+			return ErrNotFlagged
+		}
+	}
+	if dispute.CreatedAt.IsZero() {
+		dispute.CreatedAt = time.Now()
+	}
+
+	fs.mu.Lock()
+	if fs.undecided[dispute.RuleID][dispute.UserID] == 0 {
+		fs.mu.Unlock()
+		return ErrNotShown
+	}
+	fs.undecided[dispute.RuleID][dispute.UserID]--
+	fs.addDisputer(dispute.RuleID, dispute.UserID)
+	feedback := fs.ruleFeedback(dispute.RuleID)
+	feedback.Disputed++
+	feedback.Examples = append([]string{dispute.Text}, feedback.Examples...)
+	if len(feedback.Examples) > maxDisputeExamples {
+		feedback.Examples = feedback.Examples[:maxDisputeExamples]
+	}
+	fs.mu.Unlock()
+
+	log.Printf("User %s disputed %s on %q", dispute.UserID, dispute.RuleID, dispute.Text)
+
+	if fs.db != nil {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), persistTimeout)
+			defer cancel()
+			if err := fs.db.Insert(ctx, disputesTable, dispute); err != nil {
+				log.Printf("Error saving dispute: %v", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// Adjust recomputes every rule's false-positive rate, lowers the confidence
+// of rules past demoteRate, switches off rules past disableRate, and saves
+// the result. It returns the feedback of the rules it changed.
+func (fs *FeedbackService) Adjust(ctx context.Context) []RuleFeedback {
+	baseRules := rules.Base()
+	overrides := make(map[string]rules.Override)
+	changed := make([]RuleFeedback, 0)
+	rows := make([]RuleFeedback, 0)
+
+	fs.mu.Lock()
+	for id, feedback := range fs.feedback {
+		feedback.FalsePositiveRate = feedback.falsePositiveRate()
+		feedback.Confidence, feedback.Disabled = 0, false
+		feedback.UpdatedAt = time.Now()
+
+		rule := baseRules.Lookup(id)
+		if rule != nil && feedback.Disputers >= minDisputersToAdjust {
+			reason := fmt.Sprintf("%d of %d findings disputed", feedback.Disputed, feedback.judged())
+			switch {
+			case feedback.FalsePositiveRate >= disableRate:
+				feedback.Disabled = true
+				overrides[id] = rules.Override{Disabled: true, Reason: reason}
+			case feedback.FalsePositiveRate >= demoteRate:
+				feedback.Confidence = rule.Confidence * (1 - feedback.FalsePositiveRate)
+				overrides[id] = rules.Override{Confidence: feedback.Confidence, Reason: reason}
+			}
+		}
+		if feedback.Disabled || feedback.Confidence > 0 {
+			changed = append(changed, *feedback)
+		}
+
+		row := *feedback
+		row.Examples = nil
+		rows = append(rows, row)
+	}
+	fs.mu.Unlock()
+
+	rules.SetOverrides(overrides)
+	for _, feedback := range changed {
+		if feedback.Disabled {
+			log.Printf("Disabled rule %s: %.0f%% of its findings disputed", feedback.RuleID, feedback.FalsePositiveRate*100)
+		} else {
+			log.Printf("Lowered confidence of rule %s to %.2f: %.0f%% of its findings disputed", feedback.RuleID, feedback.Confidence, feedback.FalsePositiveRate*100)
+		}
+	}
+
+	if fs.db != nil && len(rows) > 0 {
+		if err := fs.db.Upsert(ctx, ruleFeedbackTable, rows); err != nil {
+			log.Printf("Error saving rule feedback: %v", err)
+		}
+	}
+
+	return changed
+}
+
+// Run adjusts the rules every interval, forever
+func (fs *FeedbackService) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), persistTimeout)
+		fs.Adjust(ctx)
+		cancel()
+	}
+}
+
+// Report returns the feedback of the most disputed rules, at most limit of
+// them, most disputed first
+func (fs *FeedbackService) Report(limit int) []RuleFeedback {
+	fs.mu.Lock()
+	report := make([]RuleFeedback, 0, len(fs.feedback))
+	for _, feedback := range fs.feedback {
+		if feedback.Disputed > 0 {
+			entry := *feedback
+			entry.FalsePositiveRate = feedback.falsePositiveRate()
+			entry.Examples = append([]string(nil), feedback.Examples...)
+			report = append(report, entry)
+		}
+	}
+	fs.mu.Unlock()
+
+	sort.Slice(report, func(a, b int) bool {
+		if report[a].Disputed != report[b].Disputed {
+			return report[a].Disputed > report[b].Disputed
+		}
+		if report[a].FalsePositiveRate != report[b].FalsePositiveRate {
+			return report[a].FalsePositiveRate > report[b].FalsePositiveRate
+		}
+		return report[a].RuleID < report[b].RuleID
+	})
+
+	if limit > 0 && len(report) > limit {
+		report = report[:limit]
+	}
+	return report
+}
+
+// ruleFeedback returns the feedback of a rule, creating it if needed.
+// Caller must hold fs.mu.
+func (fs *FeedbackService) ruleFeedback(ruleID string) *RuleFeedback {
+	feedback, ok := fs.feedback[ruleID]
+	if !ok {
+		feedback = &RuleFeedback{RuleID: ruleID}
+		fs.feedback[ruleID] = feedback
+	}
+	return feedback
+}
+
+// addDisputer notes that a user disputed a finding of a rule. Caller must
+// hold fs.mu.
+func (fs *FeedbackService) addDisputer(ruleID, userID string) {
+	users, ok := fs.disputers[ruleID]
+	if !ok {
+		users = make(map[string]bool)
+		fs.disputers[ruleID] = users
+	}
+	users[userID] = true
+	fs.ruleFeedback(ruleID).Disputers = len(users)
+}
+
+// judged is the number of findings users could have disputed. Disputes can
+// outnumber the recorded findings after a restart, so they count too.
+func (f *RuleFeedback) judged() int {
+	return max(f.Shown, f.Disputed)
+}
+
+// falsePositiveRate is the share of the rule's findings users disputed
+func (f *RuleFeedback) falsePositiveRate() float64 {
+	if f.judged() == 0 {
+		return 0
+	}
+	return float64(f.Disputed) / float64(f.judged())
+}
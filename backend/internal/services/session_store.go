@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/supabase"
+)
+
+// sessionsTable holds one row per practice or interview session
+const sessionsTable = "sessions"
+
+// sessionDomains are the domains the sessions table accepts
+var sessionDomains = map[string]bool{
+	"Tech": true, "Finance": true, "UPSC": true, "SSC": true, "NDA": true,
+	"CDS": true, "Business/MBA": true, "General": true,
+}
+
+// SessionStore saves finished sessions with their grammar score
+type SessionStore struct {
+	db *supabase.Client
+}
+
+// SessionRecord is a finished session as the sessions table holds it
+type SessionRecord struct {
+	ID               string          `json:"id"`
+	UserID           string          `json:"user_id,omitempty"`
+	Mode             string          `json:"mode"` // practice or interview
+	Domain           string          `json:"domain,omitempty"`
+	DurationSeconds  int             `json:"duration_seconds"`
+	ErrorsCount      int             `json:"errors_count"`
+	GrammarScore     *float64        `json:"grammar_score"` // Nil when nothing was said
+	GrammarBreakdown *ScoreBreakdown `json:"grammar_breakdown"`
+	StartedAt        time.Time       `json:"started_at"`
+	EndedAt          time.Time       `json:"ended_at"`
+}
+
+// NewSessionStore creates a session store; db may be nil, in which case
+// sessions are not saved
+func NewSessionStore(db *supabase.Client) *SessionStore {
+	return &SessionStore{db: db}
+}
+
+// NewSessionRecord describes a session that ran from startedAt until now,
+// saved under recordID, a UUID. Sessions with a domain are interviews,
+// others practice; domains the table doesn't accept are left out, and so
+// are user IDs that aren't UUIDs, such as "anonymous".
+func NewSessionRecord(recordID, userID, domain string, startedAt time.Time, errorsCount int, score *GrammarScore) SessionRecord {
+	record := SessionRecord{
+		ID:              recordID,
+		Mode:            "practice",
+		DurationSeconds: int(time.Since(startedAt).Seconds()),
+		ErrorsCount:     errorsCount,
+		StartedAt:       startedAt,
+		EndedAt:         time.Now(),
+	}
+	if _, err := uuid.Parse(userID); err == nil {
+		record.UserID = userID
+	}
+	if domain != "" {
+		record.Mode = "interview"
+	}
+	if sessionDomains[domain] {
+		record.Domain = domain
+	}
+	if score != nil {
+		record.GrammarScore = &score.Score
+		record.GrammarBreakdown = &score.Breakdown
+	}
+	return record
+}
+
+// Save writes a finished session, creating its row or updating the one
+// saved under the same ID
+func (ss *SessionStore) Save(ctx context.Context, record SessionRecord) {
+	if ss.db == nil || record.ID == "" {
+		return
+	}
+	if err := ss.db.Upsert(ctx, sessionsTable, record); err != nil {
+		log.Printf("Error saving session %s: %v", record.ID, err)
+	}
+}
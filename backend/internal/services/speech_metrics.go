@@ -0,0 +1,169 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/rules"
+)
+
+const (
+	// maxUtteranceGap caps the wall-clock time an utterance without word
+	// timings is taken to have lasted. Longer gaps since the previous
+	// utterance are the speaker listening or thinking, not talking.
+	maxUtteranceGap = 20 * time.Second
+
+	// minSentenceWords is the fewest words a complete sentence has
+	minSentenceWords = 2
+)
+
+var (
+	// finiteModals make a clause finite on their own: "I can"
+	finiteModals = wordSet(`will would can could shall should may might must`)
+
+	// danglingEndings leave a sentence unfinished when it ends on them:
+	// "I went there because", "I want to"
+	danglingEndings = wordSet(`and but or so because although though while if when that which who whose
+		the a an my your his her our their this these those to of for with in on at from by about as than`)
+
+	// finiteContractions are the endings of contracted verbs: "I'm", "she's"
+	finiteContractions = []string{"'m", "'re", "'s", "'ve", "'ll", "'d", "n't"}
+)
+
+// SpeechMetrics describe how much and how fully a speaker talks over a
+// session, from its final transcripts
+type SpeechMetrics struct {
+	Utterances            int     `json:"utterances"`
+	WordCount             int     `json:"word_count"` // Words said, without fillers such as "um"
+	SpeakingSeconds       float64 `json:"speaking_seconds"`
+	WordsPerMinute        float64 `json:"words_per_minute"`
+	MeanLengthOfUtterance float64 `json:"mean_length_of_utterance"` // Words per utterance
+	TypeTokenRatio        float64 `json:"type_token_ratio"`         // Different words over words said
+	Sentences             int     `json:"sentences"`
+	CompleteSentences     int     `json:"complete_sentences"`
+	CompleteSentenceShare float64 `json:"complete_sentence_share"` // 0 to 1
+	TimedUtterances       int     `json:"timed_utterances"`        // Utterances timed by the STT's word timings rather than the clock
+}
+
+// utteranceRecord is one final transcript of a session
+type utteranceRecord struct {
+	at        time.Time     // When the final transcript arrived
+	words     int           // Words without fillers
+	duration  time.Duration // Time spent saying it
+	timed     bool          // duration comes from word timings
+	sentences int
+	complete  int // Sentences that look complete
+}
+
+// speechUsage holds the utterances and word types of a session
+type speechUsage struct {
+	startedAt  time.Time
+	utterances []utteranceRecord
+	types      map[string]bool
+}
+
+// newSpeechUsage creates empty speech records for a session starting now
+func newSpeechUsage() *speechUsage {
+	return &speechUsage{
+		startedAt: time.Now(),
+		types:     make(map[string]bool),
+	}
+}
+
+// record adds a final transcript. words are the STT's word timings; without
+// them the utterance is taken to have lasted since the previous one, up to
+// maxUtteranceGap.
+func (u *speechUsage) record(text string, words []TranscriptWord, at time.Time) {
+	utterance := utteranceRecord{at: at}
+	for _, word := range correctionWords(text) {
+		if fillerWords[word] {
+			continue
+		}
+		utterance.words++
+		u.types[word] = true
+	}
+	if utterance.words == 0 {
+		return
+	}
+
+	if len(words) > 0 && words[len(words)-1].End > words[0].Start {
+		utterance.duration = time.Duration((words[len(words)-1].End - words[0].Start) * float64(time.Second))
+		utterance.timed = true
+	} else {
+		previous := u.startedAt
+		if len(u.utterances) > 0 {
+			previous = u.utterances[len(u.utterances)-1].at
+		}
+		utterance.duration = min(at.Sub(previous), maxUtteranceGap)
+	}
+
+	for _, sentence := range rules.Sentences(text) {
+		utterance.sentences++
+		if completeSentence(sentence.Text) {
+			utterance.complete++
+		}
+	}
+
+	u.utterances = append(u.utterances, utterance)
+}
+
+// metrics computes the session's metrics so far
+func (u *speechUsage) metrics() SpeechMetrics {
+	metrics := SpeechMetrics{Utterances: len(u.utterances)}
+	var speaking time.Duration
+	for _, utterance := range u.utterances {
+		metrics.WordCount += utterance.words
+		metrics.Sentences += utterance.sentences
+		metrics.CompleteSentences += utterance.complete
+		speaking += utterance.duration
+		if utterance.timed {
+			metrics.TimedUtterances++
+		}
+	}
+
+	metrics.SpeakingSeconds = speaking.Seconds()
+	if speaking > 0 {
+		metrics.WordsPerMinute = float64(metrics.WordCount) / speaking.Minutes()
+	}
+	if metrics.Utterances > 0 {
+		metrics.MeanLengthOfUtterance = float64(metrics.WordCount) / float64(metrics.Utterances)
+	}
+	if metrics.WordCount > 0 {
+		metrics.TypeTokenRatio = float64(len(u.types)) / float64(metrics.WordCount)
+	}
+	if metrics.Sentences > 0 {
+		metrics.CompleteSentenceShare = float64(metrics.CompleteSentences) / float64(metrics.Sentences)
+	}
+	return metrics
+}
+
+// completeSentence reports whether a sentence looks complete: it has a
+// finite verb, a modal or a contracted verb, and doesn't stop on a word
+// that needs more after it. Verbs outside the rules' lexicon are missed.
+func completeSentence(text string) bool {
+	words := correctionWords(text)
+	content := make([]string, 0, len(words))
+	for _, word := range words {
+		if !fillerWords[word] {
+			content = append(content, word)
+		}
+	}
+	if len(content) < minSentenceWords || danglingEndings[content[len(content)-1]] {
+		return false
+	}
+
+	for _, word := range content {
+		if finiteModals[word] {
+			return true
+		}
+		for _, ending := range finiteContractions {
+			if strings.HasSuffix(word, ending) && len(word) > len(ending) {
+				return true
+			}
+		}
+		if _, forms, ok := rules.LookupVerb(word); ok && forms&(rules.FormBase|rules.FormThird|rules.FormPast) != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/messages"
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/rules"
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/supabase"
+)
+
+// DefaultTranslationCacheSize is how many translations are kept in memory
+const DefaultTranslationCacheSize = 2000
+
+// Supabase table holding the cached translations, see supabase/migrations
+const translationsTable = "translation_cache"
+
+// defaultExplanationStyle is the style of explanations for users and
+// sessions that choose none
+const defaultExplanationStyle = StyleNative
+
+// ErrTranslationNotFound is returned when editing a translation that is not cached
+var ErrTranslationNotFound = errors.New("translation not found")
+
+// TranslationKey identifies a translated explanation
+type TranslationKey struct {
+	English  string `json:"english"`
+	Language string `json:"language"`
+	Style    string `json:"style"`
+}
+
+// CachedTranslation is an LLM translation of an English explanation,
+// possibly edited and approved by an admin
+type CachedTranslation struct {
+	TranslationKey
+	RuleID      string    `json:"rule_id"` // The rule the explanation was for, LLMRuleID for LLM findings
+	Translation string    `json:"translation"`
+	Approved    bool      `json:"approved"`
+	Edited      bool      `json:"edited"` // Changed by an admin since the LLM wrote it
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TranslationEdit changes a cached translation; nil fields are left alone
+type TranslationEdit struct {
+	Translation *string `json:"translation"`
+	Approved    *bool   `json:"approved"`
+}
+
+// TranslationFilter selects cached translations for review
+type TranslationFilter struct {
+	Language string
+	Approved *bool // Only approved or only unapproved translations, nil for both
+	Limit    int
+}
+
+// TranslationCache remembers the LLM's translations of explanations the
+// message catalogue lacks, so each is only asked for once. The most recently
+// used translations are held in memory in front of Supabase; without a
+// Supabase client the cache lives in memory only.
+type TranslationCache struct {
+	db       *supabase.Client
+	capacity int
+	entries  map[TranslationKey]*list.Element
+	order    *list.List // Of *CachedTranslation, most recently used first
+	mu       sync.Mutex
+}
+
+// NewTranslationCache creates a cache holding up to capacity translations in
+// memory; db may be nil
+func NewTranslationCache(db *supabase.Client, capacity int) *TranslationCache {
+	if capacity <= 0 {
+		capacity = DefaultTranslationCacheSize
+	}
+
+	return &TranslationCache{
+		db:       db,
+		capacity: capacity,
+		entries:  make(map[TranslationKey]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns a copy of the cached translation for key, looking in Supabase
+// when it is not in memory
+func (tc *TranslationCache) Get(ctx context.Context, key TranslationKey) (*CachedTranslation, bool) {
+	key = key.normalised()
+
+	tc.mu.Lock()
+	if element, ok := tc.entries[key]; ok {
+		tc.order.MoveToFront(element)
+		entry := *element.Value.(*CachedTranslation)
+		tc.mu.Unlock()
+		return &entry, true
+	}
+	tc.mu.Unlock()
+
+	if tc.db == nil || ctx.Err() != nil {
+		return nil, false
+	}
+
+	var rows []CachedTranslation
+	query := key.filter()
+	query.Set("select", "*")
+	query.Set("limit", "1")
+	if err := tc.db.Select(ctx, translationsTable, query, &rows); err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Error reading translation cache: %v", err)
+		}
+		return nil, false
+	}
+	if len(rows) == 0 {
+		return nil, false
+	}
+
+	tc.remember(&rows[0])
+	return &rows[0], true
+}
+
+// Put caches a new LLM translation and saves it in the background
+func (tc *TranslationCache) Put(key TranslationKey, ruleID, translation string) {
+	now := time.Now()
+	entry := &CachedTranslation{
+		TranslationKey: key.normalised(),
+		RuleID:         ruleID,
+		Translation:    translation,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	tc.remember(entry)
+
+	if tc.db != nil {
+		row := *entry
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), persistTimeout)
+			defer cancel()
+			if err := tc.db.Upsert(ctx, translationsTable, row); err != nil {
+				log.Printf("Error saving translation: %v", err)
+			}
+		}()
+	}
+}
+
+// Update edits or approves a cached translation and saves it before
+// returning. The cache in memory only changes once the save succeeded.
+func (tc *TranslationCache) Update(ctx context.Context, key TranslationKey, edit TranslationEdit) (*CachedTranslation, error) {
+	entry, ok := tc.Get(ctx, key)
+	if !ok {
+		return nil, ErrTranslationNotFound
+	}
+
+	if edit.Translation != nil && *edit.Translation != entry.Translation {
+		entry.Translation = *edit.Translation
+		entry.Edited = true
+	}
+	if edit.Approved != nil {
+		entry.Approved = *edit.Approved
+	}
+	entry.UpdatedAt = time.Now()
+
+	if tc.db != nil {
+		values := map[string]interface{}{
+			"translation": entry.Translation,
+			"approved":    entry.Approved,
+			"edited":      entry.Edited,
+			"updated_at":  entry.UpdatedAt,
+		}
+		if err := tc.db.Update(ctx, translationsTable, entry.TranslationKey.filter(), values); err != nil {
+			return nil, err
+		}
+	}
+
+	tc.remember(entry)
+	return entry, nil
+}
+
+// List returns the cached translations matching filter, most recently
+// updated first. Without Supabase only the translations in memory are listed.
+func (tc *TranslationCache) List(ctx context.Context, filter TranslationFilter) ([]CachedTranslation, error) {
+	language := strings.ToLower(strings.TrimSpace(filter.Language))
+
+	if tc.db != nil {
+		query := url.Values{
+			"select": {"*"},
+			"order":  {"updated_at.desc"},
+		}
+		if language != "" {
+			query.Set("language", "eq."+language)
+		}
+		if filter.Approved != nil {
+			query.Set("approved", "eq."+strconv.FormatBool(*filter.Approved))
+		}
+		if filter.Limit > 0 {
+			query.Set("limit", strconv.Itoa(filter.Limit))
+		}
+
+		rows := make([]CachedTranslation, 0)
+		if err := tc.db.Select(ctx, translationsTable, query, &rows); err != nil {
+			return nil, err
+		}
+		return rows, nil
+	}
+
+	tc.mu.Lock()
+	rows := make([]CachedTranslation, 0, tc.order.Len())
+	for element := tc.order.Front(); element != nil; element = element.Next() {
+		entry := element.Value.(*CachedTranslation)
+		if language != "" && entry.Language != language {
+			continue
+		}
+		if filter.Approved != nil && entry.Approved != *filter.Approved {
+			continue
+		}
+		rows = append(rows, *entry)
+	}
+	tc.mu.Unlock()
+
+	sort.SliceStable(rows, func(a, b int) bool {
+		return rows[a].UpdatedAt.After(rows[b].UpdatedAt)
+	})
+	if filter.Limit > 0 && len(rows) > filter.Limit {
+		rows = rows[:filter.Limit]
+	}
+	return rows, nil
+}
+
+// CatalogueFile turns the approved translations of a language into a
+// message catalogue file, ready to merge into internal/messages/data. Only
+// translations of a rule's own description are included, as those of
+// explanations written for one match do not fit the rule's other matches,
+// and rules the catalogue already explains in the language are skipped.
+// Translations in other styles go under the file's styles.
+func (tc *TranslationCache) CatalogueFile(ctx context.Context, language string, catalogue *messages.Catalogue) (*messages.File, error) {
+	approved := true
+	entries, err := tc.List(ctx, TranslationFilter{Language: language, Approved: &approved})
+	if err != nil {
+		return nil, err
+	}
+
+	file := &messages.File{
+		Version:  1,
+		Language: strings.TrimSpace(language),
+		Messages: make(map[string]string),
+	}
+	existing := catalogue.Language(language)
+	if existing != nil {
+		file.Language = existing.Name
+		file.Fallback = existing.Fallback
+	}
+
+	ruleSet := rules.Base()
+	for _, entry := range entries {
+		rule := ruleSet.Lookup(entry.RuleID)
+		if rule == nil || entry.English != rule.Description {
+			continue
+		}
+
+		exported := file.Messages
+		if entry.Style != StyleNative {
+			if file.Styles == nil {
+				file.Styles = make(map[string]map[string]string)
+			}
+			if file.Styles[entry.Style] == nil {
+				file.Styles[entry.Style] = make(map[string]string)
+			}
+			exported = file.Styles[entry.Style]
+		}
+		if existing != nil {
+			if _, ok := existing.Styles[entry.Style][entry.RuleID]; ok {
+				continue
+			}
+			if _, ok := existing.Messages[entry.RuleID]; ok && entry.Style == StyleNative {
+				continue
+			}
+		}
+		if _, ok := exported[entry.RuleID]; !ok {
+			exported[entry.RuleID] = entry.Translation
+		}
+	}
+	return file, nil
+}
+
+// remember puts a copy of entry at the front of the in-memory cache,
+// evicting the least recently used translation when it is full. Callers
+// keep their entry to change without the lock.
+func (tc *TranslationCache) remember(entry *CachedTranslation) {
+	stored := *entry
+	entry = &stored
+
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if element, ok := tc.entries[entry.TranslationKey]; ok {
+		element.Value = entry
+		tc.order.MoveToFront(element)
+		return
+	}
+
+	tc.entries[entry.TranslationKey] = tc.order.PushFront(entry)
+	for tc.order.Len() > tc.capacity {
+		oldest := tc.order.Back()
+		tc.order.Remove(oldest)
+		delete(tc.entries, oldest.Value.(*CachedTranslation).TranslationKey)
+	}
+}
+
+// normalised returns the key with the language and style lowercased, so
+// "Hindi" and "hindi" share translations
+func (k TranslationKey) normalised() TranslationKey {
+	k.English = strings.TrimSpace(k.English)
+	k.Language = strings.ToLower(strings.TrimSpace(k.Language))
+	k.Style = strings.ToLower(strings.TrimSpace(k.Style))
+	if k.Style == "" {
+		k.Style = defaultExplanationStyle
+	}
+	return k
+}
+
+// filter is the PostgREST filter selecting the row of a normalised key
+func (k TranslationKey) filter() url.Values {
+	return url.Values{
+		"english":  {"eq." + k.English},
+		"language": {"eq." + k.Language},
+		"style":    {"eq." + k.Style},
+	}
+}
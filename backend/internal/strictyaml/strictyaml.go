@@ -0,0 +1,18 @@
+// Package strictyaml decodes the YAML data files of the rules, messages
+// and vocabulary. Unknown fields are rejected so typos don't silently drop
+// settings.
+package strictyaml
+
+import (
+	"bytes"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Decode decodes one YAML document from data into out, failing on fields
+// out doesn't have
+func Decode(data []byte, out interface{}) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	return decoder.Decode(out)
+}
@@ -0,0 +1,108 @@
+package supabase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Client talks to the PostgREST API of a Supabase project with the service
+// key, so row-level security does not apply. It is only used server-side.
+type Client struct {
+	baseURL    string
+	serviceKey string
+	httpClient *http.Client
+}
+
+// NewClient creates a client from SUPABASE_URL and SUPABASE_SERVICE_KEY.
+// It returns nil if either is unset; callers then keep their data in memory.
+func NewClient() *Client {
+	baseURL := strings.TrimRight(os.Getenv("SUPABASE_URL"), "/")
+	serviceKey := os.Getenv("SUPABASE_SERVICE_KEY")
+	if baseURL == "" || serviceKey == "" {
+		return nil
+	}
+
+	return &Client{
+		baseURL:    baseURL,
+		serviceKey: serviceKey,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Insert adds rows to table. rows is a struct or a slice of structs with
+// json tags matching the column names.
+func (c *Client) Insert(ctx context.Context, table string, rows interface{}) error {
+	return c.do(ctx, http.MethodPost, table, nil, rows, "return=minimal", nil)
+}
+
+// Upsert inserts rows, updating the existing rows whose primary key they share
+func (c *Client) Upsert(ctx context.Context, table string, rows interface{}) error {
+	return c.do(ctx, http.MethodPost, table, nil, rows, "resolution=merge-duplicates,return=minimal", nil)
+}
+
+// Update sets values on the rows of table matching filter, e.g.
+// url.Values{"id": {"eq.42"}}
+func (c *Client) Update(ctx context.Context, table string, filter url.Values, values interface{}) error {
+	return c.do(ctx, http.MethodPatch, table, filter, values, "return=minimal", nil)
+}
+
+// Select decodes the rows of table matching query into out, a pointer to a
+// slice. query holds PostgREST parameters such as select, order and filters.
+func (c *Client) Select(ctx context.Context, table string, query url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodGet, table, query, nil, "", out)
+}
+
+// do sends one request to the REST endpoint of table
+func (c *Client) do(ctx context.Context, method, table string, query url.Values, body interface{}, prefer string, out interface{}) error {
+	endpoint := c.baseURL + "/rest/v1/" + table
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("apikey", c.serviceKey)
+	req.Header.Set("Authorization", "Bearer "+c.serviceKey)
+	req.Header.Set("Content-Type", "application/json")
+	if prefer != "" {
+		req.Header.Set("Prefer", prefer)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("supabase %s %s: %s: %s", method, table, resp.Status, string(message))
+	}
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
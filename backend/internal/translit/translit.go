@@ -0,0 +1,306 @@
+// Package translit writes Indian-script text in the Latin alphabet the way
+// learners type it in chats, e.g. "है" as "hai" and "நான்" as "naan". It
+// covers Devanagari, Tamil and Telugu and is deterministic: the same text
+// always gives the same spelling.
+package translit
+
+import (
+	"strings"
+	"unicode"
+)
+
+// script holds the letters of one script and how its words are spelled
+type script struct {
+	vowels      map[rune]string // Independent vowels
+	signs       map[rune]string // Dependent vowel signs
+	consonants  map[rune]string
+	nukta       map[rune]string // Consonants changed by a following nukta
+	virama      rune
+	anusvara    rune
+	candrabindu rune
+	visarga     rune
+	finalNasal  string // Anusvara at the end of a word
+	schwaDelete bool   // Drop the inherent vowel where speech drops it, as in Hindi
+	shortFinal  bool   // Write long vowels at the end of a word short, e.g. "tha" not "thaa"
+
+	// Consonants spelled otherwise between vowels, e.g. Tamil "adhu" not "athu"
+	betweenVowels map[string]string
+}
+
+var devanagari = &script{
+	vowels: map[rune]string{
+		'अ': "a", 'आ': "aa", 'इ': "i", 'ई': "ee", 'उ': "u", 'ऊ': "oo", 'ऋ': "ri",
+		'ए': "e", 'ऐ': "ai", 'ओ': "o", 'औ': "au", 'ऑ': "o", 'ऍ': "e",
+	},
+	signs: map[rune]string{
+		'ा': "aa", 'ि': "i", 'ी': "ee", 'ु': "u", 'ू': "oo", 'ृ': "ri",
+		'े': "e", 'ै': "ai", 'ो': "o", 'ौ': "au", 'ॉ': "o", 'ॅ': "e",
+	},
+	consonants: map[rune]string{
+		'क': "k", 'ख': "kh", 'ग': "g", 'घ': "gh", 'ङ': "n",
+		'च': "ch", 'छ': "chh", 'ज': "j", 'झ': "jh", 'ञ': "n",
+		'ट': "t", 'ठ': "th", 'ड': "d", 'ढ': "dh", 'ण': "n",
+		'त': "t", 'थ': "th", 'द': "d", 'ध': "dh", 'न': "n",
+		'प': "p", 'फ': "ph", 'ब': "b", 'भ': "bh", 'म': "m",
+		'य': "y", 'र': "r", 'ल': "l", 'ळ': "l", 'व': "v",
+		'श': "sh", 'ष': "sh", 'स': "s", 'ह': "h",
+		'\u0958': "q", '\u0959': "kh", '\u095A': "gh", '\u095B': "z", '\u095C': "d", '\u095D': "dh", '\u095E': "f", '\u095F': "y", // With nukta
+	},
+	nukta: map[rune]string{
+		'क': "q", 'ख': "kh", 'ग': "gh", 'ज': "z", 'ड': "d", 'ढ': "dh", 'फ': "f", 'य': "y",
+	},
+	virama:      '्',
+	anusvara:    'ं',
+	candrabindu: 'ँ',
+	visarga:     'ः',
+	finalNasal:  "n",
+	schwaDelete: true,
+	shortFinal:  true,
+}
+
+var tamil = &script{
+	vowels: map[rune]string{
+		'அ': "a", 'ஆ': "aa", 'இ': "i", 'ஈ': "ee", 'உ': "u", 'ஊ': "oo",
+		'எ': "e", 'ஏ': "e", 'ஐ': "ai", 'ஒ': "o", 'ஓ': "o", 'ஔ': "au",
+	},
+	signs: map[rune]string{
+		'ா': "aa", 'ி': "i", 'ீ': "ee", 'ு': "u", 'ூ': "oo",
+		'ெ': "e", 'ே': "e", 'ை': "ai", 'ொ': "o", 'ோ': "o", 'ௌ': "au",
+	},
+	consonants: map[rune]string{
+		'க': "k", 'ங': "ng", 'ச': "ch", 'ஞ': "nj", 'ட': "t", 'ண': "n",
+		'த': "th", 'ந': "n", 'ப': "p", 'ம': "m", 'ய': "y", 'ர': "r",
+		'ல': "l", 'வ': "v", 'ழ': "zh", 'ள': "l", 'ற': "r", 'ன': "n",
+		'ஜ': "j", 'ஶ': "sh", 'ஷ': "sh", 'ஸ': "s", 'ஹ': "h",
+	},
+	virama:        '்',
+	anusvara:      'ஂ',
+	visarga:       'ஃ',
+	finalNasal:    "m",
+	betweenVowels: map[string]string{"ch": "s", "t": "d", "th": "dh"},
+}
+
+var telugu = &script{
+	vowels: map[rune]string{
+		'అ': "a", 'ఆ': "aa", 'ఇ': "i", 'ఈ': "ee", 'ఉ': "u", 'ఊ': "oo", 'ఋ': "ru",
+		'ఎ': "e", 'ఏ': "e", 'ఐ': "ai", 'ఒ': "o", 'ఓ': "o", 'ఔ': "au",
+	},
+	signs: map[rune]string{
+		'ా': "aa", 'ి': "i", 'ీ': "ee", 'ు': "u", 'ూ': "oo", 'ృ': "ru",
+		'ె': "e", 'ే': "e", 'ై': "ai", 'ొ': "o", 'ో': "o", 'ౌ': "au",
+	},
+	consonants: map[rune]string{
+		'క': "k", 'ఖ': "kh", 'గ': "g", 'ఘ': "gh", 'ఙ': "n",
+		'చ': "ch", 'ఛ': "chh", 'జ': "j", 'ఝ': "jh", 'ఞ': "ny",
+		'ట': "t", 'ఠ': "th", 'డ': "d", 'ఢ': "dh", 'ణ': "n",
+		'త': "t", 'థ': "th", 'ద': "d", 'ధ': "dh", 'న': "n",
+		'ప': "p", 'ఫ': "ph", 'బ': "b", 'భ': "bh", 'మ': "m",
+		'య': "y", 'ర': "r", 'ఱ': "r", 'ల': "l", 'ళ': "l", 'వ': "v",
+		'శ': "sh", 'ష': "sh", 'స': "s", 'హ': "h",
+	},
+	virama:      '్',
+	anusvara:    'ం',
+	candrabindu: 'ఁ',
+	visarga:     'ః',
+	finalNasal:  "m",
+}
+
+// scriptOf returns the script a rune belongs to, or nil
+func scriptOf(r rune) *script {
+	switch {
+	case r >= 0x0900 && r <= 0x097F:
+		return devanagari
+	case r >= 0x0B80 && r <= 0x0BFF:
+		return tamil
+	case r >= 0x0C00 && r <= 0x0C7F:
+		return telugu
+	}
+	return nil
+}
+
+// Latin transliterates the Devanagari, Tamil and Telugu in text and leaves
+// everything else, including English words in code-mixed text, as it is.
+// Danda becomes a full stop and native digits become 0-9.
+func Latin(text string) string {
+	var b strings.Builder
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		s := scriptOf(runes[i])
+		if s == nil {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		// A word is a run of letters and marks of one script
+		j := i
+		for j < len(runes) && scriptOf(runes[j]) == s && !isPunctuation(runes[j]) {
+			j++
+		}
+		if j == i {
+			b.WriteString(punctuation(runes[i]))
+			i++
+			continue
+		}
+		b.WriteString(s.word(runes[i:j]))
+		i = j
+	}
+	return b.String()
+}
+
+// IsLatin reports whether every letter in text is Latin, i.e. the text
+// needs no further transliteration
+func IsLatin(text string) bool {
+	for _, r := range text {
+		if unicode.IsLetter(r) && !unicode.Is(unicode.Latin, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isPunctuation reports whether r is a danda or a native digit
+func isPunctuation(r rune) bool {
+	return punctuation(r) != ""
+}
+
+// punctuation returns the Latin form of a danda or native digit, or ""
+func punctuation(r rune) string {
+	switch {
+	case r == '।' || r == '॥':
+		return "."
+	case r >= '०' && r <= '९':
+		return string('0' + (r - '०'))
+	case r >= '௦' && r <= '௯':
+		return string('0' + (r - '௦'))
+	case r >= '౦' && r <= '౯':
+		return string('0' + (r - '౦'))
+	}
+	return ""
+}
+
+// syllable is a consonant cluster member or vowel with what follows it
+type syllable struct {
+	consonant string
+	vowel     string
+	inherent  bool   // vowel is the inherent "a" no sign replaced
+	coda      string // Nasal or visarga after the vowel
+	nasal     bool   // coda is an anusvara, spelled by the next letter
+}
+
+// word transliterates one word of the script
+func (s *script) word(runes []rune) string {
+	syllables := make([]*syllable, 0, len(runes))
+	var last *syllable
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case s.consonants[r] != "":
+			last = &syllable{consonant: s.consonants[r], vowel: "a", inherent: true}
+			if i+1 < len(runes) && runes[i+1] == '\u093C' && s.nukta[r] != "" {
+				last.consonant = s.nukta[r]
+				i++
+			}
+			syllables = append(syllables, last)
+		case s.vowels[r] != "":
+			last = &syllable{vowel: s.vowels[r]}
+			syllables = append(syllables, last)
+		case last == nil:
+			// A mark with nothing to attach to
+		case s.signs[r] != "":
+			last.vowel, last.inherent = s.signs[r], false
+		case r == s.virama:
+			last.vowel, last.inherent = "", false
+		case r == s.anusvara || (r == s.candrabindu && s.candrabindu != 0):
+			last.nasal = true
+		case r == s.visarga:
+			last.coda = "h"
+		}
+	}
+	if len(syllables) == 0 {
+		return ""
+	}
+
+	if s.schwaDelete {
+		deleteSchwas(syllables)
+	}
+	if final := syllables[len(syllables)-1]; s.shortFinal && final.consonant != "" {
+		switch final.vowel {
+		case "aa":
+			final.vowel = "a"
+		case "ee":
+			final.vowel = "i"
+		case "oo":
+			final.vowel = "u"
+		}
+	}
+
+	var b strings.Builder
+	for i, syl := range syllables {
+		consonant := syl.consonant
+		if alt, ok := s.betweenVowels[consonant]; ok && i > 0 && syllables[i-1].vowel != "" && !syllables[i-1].nasal && syl.vowel != "" {
+			consonant = alt
+		}
+		// A doubled consonant is written with its first letter doubled, e.g. "tth"
+		if syl.vowel == "" && i+1 < len(syllables) && syllables[i+1].consonant == syl.consonant && len(consonant) > 1 {
+			consonant = consonant[:1]
+		}
+		b.WriteString(consonant)
+		b.WriteString(syl.vowel)
+		if syl.nasal {
+			b.WriteString(s.nasalBefore(syllables, i))
+		}
+		b.WriteString(syl.coda)
+	}
+	return b.String()
+}
+
+// nasalBefore spells an anusvara by the letter after it: "m" before p, b
+// and m, "n" before other consonants and the script's own at the end
+func (s *script) nasalBefore(syllables []*syllable, i int) string {
+	if i+1 >= len(syllables) || syllables[i+1].consonant == "" {
+		return s.finalNasal
+	}
+	switch syllables[i+1].consonant[0] {
+	case 'p', 'b', 'm':
+		return "m"
+	}
+	return "n"
+}
+
+// deleteSchwas drops the inherent vowels Hindi doesn't pronounce: the one
+// ending a word unless a cluster comes before it, and, from the right, one
+// between a vowel and a consonant that has its own vowel, e.g. "kamra" not
+// "kamara"
+func deleteSchwas(syllables []*syllable) {
+	n := len(syllables)
+	// After a cluster the vowel stays, e.g. "vaakya"
+	if final := syllables[n-1]; n > 1 && final.inherent && !final.nasal && final.coda == "" && syllables[n-2].vowel != "" {
+		final.vowel, final.inherent = "", false
+	}
+
+	for i := n - 2; i >= 1; i-- {
+		syl, prev, next := syllables[i], syllables[i-1], syllables[i+1]
+		if !syl.inherent || syl.nasal || syl.coda != "" || syl.consonant == "" {
+			continue
+		}
+		if prev.vowel == "" || next.consonant == "" || next.vowel == "" {
+			continue
+		}
+		syl.vowel, syl.inherent = "", false
+	}
+}
+
+// languages are the languages written in the scripts Latin covers
+var languages = map[string]bool{
+	"hindi": true, "marathi": true, "nepali": true, "konkani": true,
+	"sanskrit": true, "maithili": true, "bhojpuri": true,
+	"tamil": true, "telugu": true,
+}
+
+// Covers reports whether a language is written in a script Latin covers
+func Covers(language string) bool {
+	return languages[strings.ToLower(strings.TrimSpace(language))]
+}
@@ -0,0 +1,236 @@
+// Package vocabulary holds the basic words speakers overuse, e.g. "very
+// good" and "a lot of", with stronger words to use instead graded by CEFR
+// level, and finds them in transcripts.
+package vocabulary
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/rules"
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/strictyaml"
+)
+
+//go:embed data/*.yaml
+var defaultFiles embed.FS
+
+// DefaultMinUses is how many times a word is said in a session before a
+// tip, for entries that don't set min_uses
+const DefaultMinUses = 3
+
+// File is the on-disk format of a word list
+type File struct {
+	Version int         `yaml:"version"`
+	Words   []EntrySpec `yaml:"words"`
+}
+
+// EntrySpec is one basic word as written in a word list
+type EntrySpec struct {
+	Phrase   string                   `yaml:"phrase"`
+	Forms    []string                 `yaml:"forms,omitempty"`
+	MinUses  int                      `yaml:"min_uses,omitempty"`
+	Synonyms map[rules.Level][]string `yaml:"synonyms"`
+	Example  string                   `yaml:"example,omitempty"`
+}
+
+// Entry is a basic word with its graded replacements
+type Entry struct {
+	Phrase   string    `json:"phrase"`
+	MinUses  int       `json:"min_uses"`
+	Synonyms []Synonym `json:"synonyms"` // Easiest level first
+	Example  string    `json:"example,omitempty"`
+
+	forms [][]string // Every form, split into words
+}
+
+// Synonym is a word to use instead of a basic one
+type Synonym struct {
+	Word  string      `json:"word"`
+	Level rules.Level `json:"level"`
+}
+
+// Use is one occurrence of a basic word in a text
+type Use struct {
+	Entry     *Entry
+	Text      string // The words as said
+	WordStart int    // Index of the first word in the text
+}
+
+// List is the set of basic words. It is immutable once loaded.
+type List struct {
+	entries []*Entry
+	byWord  map[string][]*Entry // Entries by the first word of each form
+}
+
+var defaultList *List
+
+func init() {
+	list, err := LoadDefault()
+	if err != nil {
+		panic(fmt.Sprintf("vocabulary: invalid built-in word list: %v", err))
+	}
+	defaultList = list
+}
+
+// Default returns the word list built into the binary
+func Default() *List {
+	return defaultList
+}
+
+// LoadDefault compiles the word lists built into the binary
+func LoadDefault() (*List, error) {
+	entries, err := fs.ReadDir(defaultFiles, "data")
+	if err != nil {
+		return nil, err
+	}
+
+	list := &List{byWord: make(map[string][]*Entry)}
+	for _, entry := range entries {
+		data, err := fs.ReadFile(defaultFiles, path.Join("data", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if err := list.parse(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+	}
+	return list, nil
+}
+
+// parse decodes and validates a word list and adds its entries
+func (l *List) parse(data []byte) error {
+	var file File
+	if err := strictyaml.Decode(data, &file); err != nil {
+		return err
+	}
+	if file.Version != 1 {
+		return fmt.Errorf("unsupported word list version %d", file.Version)
+	}
+
+	seen := make(map[string]bool)
+	for _, existing := range l.entries {
+		for _, form := range existing.forms {
+			seen[strings.Join(form, " ")] = true
+		}
+	}
+
+	for _, spec := range file.Words {
+		entry, err := compileEntry(spec)
+		if err != nil {
+			return err
+		}
+		for _, form := range entry.forms {
+			key := strings.Join(form, " ")
+			if seen[key] {
+				return fmt.Errorf("%s: %q is listed twice", spec.Phrase, key)
+			}
+			seen[key] = true
+			l.byWord[form[0]] = append(l.byWord[form[0]], entry)
+		}
+		l.entries = append(l.entries, entry)
+	}
+	return nil
+}
+
+// compileEntry checks an entry and orders its synonyms by level
+func compileEntry(spec EntrySpec) (*Entry, error) {
+	phrase := strings.Join(Words(spec.Phrase), " ")
+	if phrase == "" {
+		return nil, fmt.Errorf("an entry has no phrase")
+	}
+	if len(spec.Synonyms) == 0 {
+		return nil, fmt.Errorf("%s: no synonyms", phrase)
+	}
+	if spec.MinUses < 0 {
+		return nil, fmt.Errorf("%s: min_uses is negative", phrase)
+	}
+
+	entry := &Entry{
+		Phrase:  phrase,
+		MinUses: spec.MinUses,
+		Example: spec.Example,
+	}
+	if entry.MinUses == 0 {
+		entry.MinUses = DefaultMinUses
+	}
+	for _, form := range append([]string{spec.Phrase}, spec.Forms...) {
+		words := Words(form)
+		if len(words) == 0 {
+			return nil, fmt.Errorf("%s: empty form", phrase)
+		}
+		entry.forms = append(entry.forms, words)
+	}
+
+	levels := make([]rules.Level, 0, len(spec.Synonyms))
+	for level := range spec.Synonyms {
+		if !level.Valid() {
+			return nil, fmt.Errorf("%s: unknown level %q", phrase, level)
+		}
+		levels = append(levels, level)
+	}
+	// CEFR levels sort by name: A1, A2, B1, ...
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+	for _, level := range levels {
+		for _, word := range spec.Synonyms[level] {
+			entry.Synonyms = append(entry.Synonyms, Synonym{Word: word, Level: level})
+		}
+	}
+	return entry, nil
+}
+
+// Entries returns the basic words in the order they were listed
+func (l *List) Entries() []*Entry {
+	return l.entries
+}
+
+// Find returns the basic words said in text, in order. A word belongs to
+// at most one use, that of the longest phrase starting with it.
+func (l *List) Find(text string) []Use {
+	words := Words(text)
+	uses := make([]Use, 0)
+	for i := 0; i < len(words); {
+		entry, n := l.match(words, i)
+		if entry == nil {
+			i++
+			continue
+		}
+		uses = append(uses, Use{Entry: entry, Text: strings.Join(words[i:i+n], " "), WordStart: i})
+		i += n
+	}
+	return uses
+}
+
+// match returns the longest entry whose form starts at words[i], with the
+// number of words it covers
+func (l *List) match(words []string, i int) (*Entry, int) {
+	var best *Entry
+	bestLen := 0
+	for _, entry := range l.byWord[words[i]] {
+		for _, form := range entry.forms {
+			if len(form) > bestLen && i+len(form) <= len(words) && slices.Equal(words[i:i+len(form)], form) {
+				best, bestLen = entry, len(form)
+			}
+		}
+	}
+	return best, bestLen
+}
+
+// Words splits text into lowercase words without the punctuation around them
+func Words(text string) []string {
+	words := make([]string, 0)
+	for _, field := range strings.Fields(text) {
+		word := strings.ToLower(strings.TrimFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}))
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
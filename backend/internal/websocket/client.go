@@ -1,13 +1,17 @@
 package websocket
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	fiberws "github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
+	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/rules"
 	"github.com/yuvraj707sharma/vartalaap_V2/backend/internal/services"
 )
 
@@ -23,6 +27,9 @@ const (
 
 	// Maximum message size allowed from peer
 	maxMessageSize = 512 * 1024 // 512KB for audio chunks
+
+	// Least time between two metrics_update messages
+	metricsUpdateInterval = 15 * time.Second
 )
 
 // Client represents a WebSocket client
@@ -35,21 +42,57 @@ type Client struct {
 	// Buffered channel of outbound messages
 	send chan []byte
 
+	// Guards send against late corrections arriving after it was closed
+	sendMu sync.RWMutex
+	closed bool
+
+	// Cancelled when the connection closes, abandoning LLM calls in flight
+	ctx    context.Context
+	cancel context.CancelFunc
+
 	// User information
 	userID         string
 	nativeLanguage string
 	sessionID      string
+	profile        string // Rule profile chosen in start_session
+
+	// Explanation style chosen in start_session, else the user's saved style
+	explanationStyle string
+
+	// Explanation depth chosen in start_session, else the user's saved depth
+	explanationDepth string
+	domain           string // Session domain, which picks the interviewer persona
 
 	// Services
 	grammarDetector *services.GrammarDetector
 	deepgramService *services.DeepgramService
 	chunkAnalyzer   *services.ChunkAnalyzer
+	fluencyAnalyzer *services.FluencyAnalyzer
+	pronunciation   *services.PronunciationAnalyzer
+	feedbackService *services.FeedbackService
+	preferences     *services.UserPreferences
+	interviewer     *services.InterviewerService
+	sessions        *services.SessionStore
 
 	// Session state
 	currentTranscript string
 	errorCount        int
 	pauseStartTime    time.Time
 	isThinking        bool
+	utteranceSeq      int // Numbers utterances whose transcripts carry no utterance_id
+	sessionStart      time.Time
+	recordID          string    // UUID the session is saved under, as client session IDs aren't
+	lastMetricsUpdate time.Time // When metrics_update was last sent
+}
+
+// utteranceContext is the session state an utterance was handled in. Late
+// corrections arrive on the detector's goroutine and use it rather than the
+// client's fields, which the read loop changes at start_session and end_session.
+type utteranceContext struct {
+	sessionID   string
+	utteranceID string
+	depth       string // Explanation depth chosen for the session
+	strictness  int    // Strictness of the session's interviewer persona
 }
 
 // Message represents WebSocket messages
@@ -59,16 +102,25 @@ type Message struct {
 }
 
 // NewFiberClient creates a new Client instance with Fiber WebSocket
-func NewFiberClient(hub *Hub, conn *fiberws.Conn, userID string, nativeLanguage string, grammarDetector *services.GrammarDetector, deepgramService *services.DeepgramService, chunkAnalyzer *services.ChunkAnalyzer) *Client {
+func NewFiberClient(hub *Hub, conn *fiberws.Conn, userID string, nativeLanguage string, grammarDetector *services.GrammarDetector, deepgramService *services.DeepgramService, chunkAnalyzer *services.ChunkAnalyzer, fluencyAnalyzer *services.FluencyAnalyzer, pronunciation *services.PronunciationAnalyzer, feedbackService *services.FeedbackService, preferences *services.UserPreferences, interviewer *services.InterviewerService, sessions *services.SessionStore) *Client {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Client{
 		hub:             hub,
 		conn:            conn,
 		send:            make(chan []byte, 256),
+		ctx:             ctx,
+		cancel:          cancel,
 		userID:          userID,
 		nativeLanguage:  nativeLanguage,
 		grammarDetector: grammarDetector,
 		deepgramService: deepgramService,
 		chunkAnalyzer:   chunkAnalyzer,
+		fluencyAnalyzer: fluencyAnalyzer,
+		pronunciation:   pronunciation,
+		feedbackService: feedbackService,
+		preferences:     preferences,
+		interviewer:     interviewer,
+		sessions:        sessions,
 		errorCount:      0,
 		isThinking:      false,
 	}
@@ -77,6 +129,10 @@ func NewFiberClient(hub *Hub, conn *fiberws.Conn, userID string, nativeLanguage
 // readPump pumps messages from the WebSocket connection to the hub
 func (c *Client) ReadPump() {
 	defer func() {
+		c.cancel()
+		if c.chunkAnalyzer != nil && c.sessionID != "" {
+			c.chunkAnalyzer.EndSession(c.sessionID)
+		}
 		c.hub.unregister <- c
 		c.conn.Close()
 	}()
@@ -150,6 +206,8 @@ func (c *Client) processMessage(data []byte) {
 		c.handleEndSession(msg.Payload)
 	case "thinking_pause":
 		c.handleThinkingPause(msg.Payload)
+	case "dispute":
+		c.handleDispute(msg.Payload)
 	default:
 		log.Printf("Unknown message type: %s", msg.Type)
 	}
@@ -170,22 +228,49 @@ func (c *Client) handleTranscriptMessage(payload map[string]interface{}) {
 	}
 
 	isFinal, _ := payload["is_final"].(bool)
+	utteranceID := c.utteranceID(payload, isFinal)
 	
 	c.currentTranscript = transcript
 
-	// Check for grammar errors (this happens in < 5ms for rule-based)
-	errorResult, err := c.grammarDetector.DetectGrammarError(transcript, c.nativeLanguage)
+	if isFinal {
+		c.analyzeFluency(transcript)
+		c.analyzePronunciation(utteranceID, transcript, payload["words"])
+		c.analyzeVocabulary(utteranceID, transcript)
+		c.analyzeSpeech(utteranceID, transcript, payload["words"])
+	}
+
+	// Check for grammar errors (this happens in < 5ms for rule-based).
+	// The LLM only gets a short budget so it never stalls the read loop.
+	opts := services.DetectOptions{
+		NativeLanguage: c.nativeLanguage,
+		Profile:        c.profile,
+		Style:          c.style(),
+		Budget:         services.DefaultLLMBudget,
+	}
+	uc := c.utterance(utteranceID)
+	if isFinal {
+		opts.OnLate = func(results []*services.ErrorResult) {
+			c.sendLateCorrection(uc, transcript, results)
+		}
+	}
+	errorResults, err := c.grammarDetector.DetectGrammarErrors(c.ctx, transcript, opts)
 	if err != nil {
 		log.Printf("Error detecting grammar: %v", err)
 		return
 	}
 
-	if errorResult != nil && isFinal {
+	if len(errorResults) > 0 && isFinal {
+		errorResult := services.MostSevere(errorResults)
+
 		// Interrupt user with error correction
 		c.errorCount++
+		c.recordShown(uc, errorResults)
+		c.explainAt(uc, errorResults)
+		c.expectRepeat(uc, errorResult, errorResults)
 		
 		// Generate audio response
-		audioResponse, err := c.deepgramService.TextToSpeech(errorResult.ExplanationNative)
+		speech, voice := c.deepgramService.ExplanationSpeech(errorResult, c.nativeLanguage, c.style())
+		audioResponse, err := c.deepgramService.TextToSpeechVoice(speech, voice)
 		if err != nil {
 			log.Printf("Error generating TTS: %v", err)
 		}
@@ -195,6 +280,8 @@ func (c *Client) handleTranscriptMessage(payload map[string]interface{}) {
 			Type: "interruption",
 			Payload: map[string]interface{}{
 				"error":           errorResult,
+				"errors":          errorResults,
+				"utterance_id":    utteranceID,
 				"audio":           audioResponse,
 				"timestamp":       time.Now().UnixMilli(),
 				"latency_ms":      "< 300", // Our target latency
@@ -209,15 +296,67 @@ func (c *Client) handleTranscriptMessage(payload map[string]interface{}) {
 // handleStartSession starts a new practice session
 func (c *Client) handleStartSession(payload map[string]interface{}) {
 	sessionID, _ := payload["session_id"].(string)
+	profile, _ := payload["profile"].(string)
+
+	// Sessions without an ID would share one another's analysis
+	if sessionID == "" {
+		sessionID = uuid.NewString()
+	}
+	if c.chunkAnalyzer != nil && c.sessionID != "" && c.sessionID != sessionID {
+		c.chunkAnalyzer.EndSession(c.sessionID)
+	}
 	c.sessionID = sessionID
 	c.errorCount = 0
+	c.sessionStart = time.Now()
+	c.recordID = uuid.NewString()
+
+	responsePayload := map[string]interface{}{
+		"session_id": sessionID,
+		"message":    "Session started successfully",
+	}
+
+	// Fall back to the default profile rather than failing the session
+	if rules.Active().Profile(profile) == nil {
+		addWarning(responsePayload, fmt.Sprintf("Unknown profile %q, using %q", profile, rules.DefaultProfile))
+		profile = ""
+	}
+	if profile == "" {
+		profile = rules.DefaultProfile
+	}
+	c.profile = profile
+	responsePayload["profile"] = profile
+
+	// A style chosen for the session wins over the user's saved one
+	c.explanationStyle = ""
+	if requested, _ := payload["explanation_style"].(string); requested != "" {
+		if style, err := services.ParseExplanationStyle(requested); err == nil {
+			c.explanationStyle = style
+		} else {
+			addWarning(responsePayload, fmt.Sprintf("Unknown explanation style %q, using %q", requested, c.style()))
+		}
+	}
+	responsePayload["explanation_style"] = c.style()
+
+	// So is a depth, and without either the persona and history decide
+	c.domain, _ = payload["domain"].(string)
+	c.explanationDepth = ""
+	if requested, _ := payload["explanation_depth"].(string); requested != "" {
+		if depth, err := services.ParseExplanationDepth(requested); err == nil {
+			c.explanationDepth = depth
+		} else {
+			addWarning(responsePayload, fmt.Sprintf("Unknown explanation depth %q, using %q", requested, c.depth()))
+		}
+	}
+	responsePayload["explanation_depth"] = c.depth()
+	responsePayload["persona"] = c.interviewer.GetPersona(c.domain).Mode
+
+	if c.chunkAnalyzer != nil {
+		c.chunkAnalyzer.StartSession(sessionID, c.nativeLanguage, profile, c.style())
+	}
 
 	response := Message{
-		Type: "session_started",
-		Payload: map[string]interface{}{
-			"session_id": sessionID,
-			"message":    "Session started successfully",
-		},
+		Type:    "session_started",
+		Payload: responsePayload,
 	}
 
 	responseData, _ := json.Marshal(response)
@@ -234,14 +373,41 @@ func (c *Client) handleEndSession(payload map[string]interface{}) {
 			"message":     "Session ended successfully",
 		},
 	}
+	var score *services.GrammarScore
+	if c.chunkAnalyzer != nil {
+		if metrics := c.chunkAnalyzer.GetSpeechMetrics(c.sessionID); metrics != nil {
+			response.Payload["speech_metrics"] = metrics
+		}
+		if score = c.chunkAnalyzer.GetGrammarScore(c.sessionID); score != nil {
+			response.Payload["grammar_score"] = score.Score
+			response.Payload["grammar_breakdown"] = score.Breakdown
+		}
+	}
+
+	// The connection often closes right after, so don't tie the save to it.
+	// Without start_session there is nothing to save.
+	if c.sessions != nil && c.recordID != "" {
+		record := services.NewSessionRecord(c.recordID, c.userID, c.domain, c.sessionStart, c.errorCount, score)
+		go c.sessions.Save(context.Background(), record)
+	}
 
 	responseData, _ := json.Marshal(response)
 	c.send <- responseData
 
+	// Its analysis is in the payload and the saved record now
+	if c.chunkAnalyzer != nil {
+		c.chunkAnalyzer.EndSession(c.sessionID)
+	}
+
 	// Reset session
 	c.sessionID = ""
+	c.recordID = ""
+	c.explanationStyle = ""
+	c.explanationDepth = ""
+	c.domain = ""
 	c.errorCount = 0
 	c.currentTranscript = ""
+	c.lastMetricsUpdate = time.Time{}
 }
 
 // handleInterimTranscript processes interim (non-final) transcript chunks for real-time analysis
@@ -252,10 +418,20 @@ func (c *Client) handleInterimTranscript(payload map[string]interface{}) {
 	}
 
 	isFinal, _ := payload["is_final"].(bool)
+	utteranceID := c.utteranceID(payload, isFinal)
+
+	// Before an interruption for this utterance asks for the next to repeat it
+	if isFinal {
+		c.analyzePronunciation(utteranceID, transcript, payload["words"])
+		c.analyzeSpeech(utteranceID, transcript, payload["words"])
+	}
 
 	// Use chunk analyzer for real-time detection
 	if c.chunkAnalyzer != nil {
-		chunkError, err := c.chunkAnalyzer.AnalyzeChunk(c.sessionID, transcript, isFinal)
+		uc := c.utterance(utteranceID)
+		chunkError, err := c.chunkAnalyzer.AnalyzeChunk(c.ctx, c.sessionID, transcript, isFinal, func(late *services.ChunkError) {
+			c.sendLateCorrection(uc, late.ChunkText, late.Errors)
+		})
 		if err != nil {
 			log.Printf("Error analyzing chunk: %v", err)
 			return
@@ -263,7 +439,7 @@ func (c *Client) handleInterimTranscript(payload map[string]interface{}) {
 
 		// If error detected and it's a new error, send interruption
 		if chunkError != nil && chunkError.IsNewError {
-			c.sendInterruption(chunkError.ErrorResult, transcript)
+			c.sendInterruption(chunkError.ErrorResult, chunkError.Errors, transcript, utteranceID)
 		}
 	}
 
@@ -282,6 +458,8 @@ func (c *Client) handleInterimTranscript(payload map[string]interface{}) {
 	} else {
 		// For final transcripts, also update the current transcript
 		c.currentTranscript = transcript
+		c.analyzeFluency(transcript)
+		c.analyzeVocabulary(utteranceID, transcript)
 	}
 }
 
@@ -296,6 +474,10 @@ func (c *Client) handleThinkingPause(payload map[string]interface{}) {
 	c.isThinking = true
 	c.pauseStartTime = time.Now()
 
+	if c.fluencyAnalyzer != nil {
+		c.sendFluencyUpdate(c.fluencyAnalyzer.RecordPause(c.sessionID, pauseDuration))
+	}
+
 	// If pause is very long (> 5 seconds), send a gentle nudge
 	if pauseDuration > 5000 {
 		response := Message{
@@ -311,13 +493,19 @@ func (c *Client) handleThinkingPause(payload map[string]interface{}) {
 }
 
 // sendInterruption sends a grammar error interruption to the client
-func (c *Client) sendInterruption(errorResult *services.ErrorResult, originalText string) {
+// errorResult is spoken to the user, errorResults lists every error so the UI can highlight them
+func (c *Client) sendInterruption(errorResult *services.ErrorResult, errorResults []*services.ErrorResult, originalText string, utteranceID string) {
+	uc := c.utterance(utteranceID)
 	c.errorCount++
+	c.recordShown(uc, errorResults)
+	c.explainAt(uc, errorResults)
+	c.expectRepeat(uc, errorResult, errorResults)
 
 	// Generate audio response for the native language explanation
 	var audioResponse string
 	if c.deepgramService != nil {
-		audio, err := c.deepgramService.TextToSpeech(errorResult.ExplanationNative)
+		speech, voice := c.deepgramService.ExplanationSpeech(errorResult, c.nativeLanguage, c.style())
+		audio, err := c.deepgramService.TextToSpeechVoice(speech, voice)
 		if err != nil {
 			log.Printf("Error generating TTS: %v", err)
 		} else {
@@ -338,11 +526,26 @@ func (c *Client) sendInterruption(errorResult *services.ErrorResult, originalTex
 				"explanation_native":  errorResult.ExplanationNative,
 				"rule_id":             errorResult.RuleID,
 				"confidence":          errorResult.Confidence,
+				"category":            errorResult.Category,
+				"severity":            errorResult.Severity,
+				"start":               errorResult.Start,
+				"end":                 errorResult.End,
+				"word_start":          errorResult.WordStart,
+				"word_end":            errorResult.WordEnd,
+				"matched":             errorResult.Matched,
+				"replacement":         errorResult.Replacement,
+				"edits":               errorResult.Edits,
+				"depth":               errorResult.Depth,
+				"correction":          errorResult.Correction,
+				"lesson":              errorResult.Lesson,
+				"examples":            errorResult.Examples,
 			},
-			"audio":       audioResponse,
-			"timestamp":   time.Now().UnixMilli(),
-			"latency_ms":  "< 300",
-			"text":        originalText,
+			"errors":       errorResults,
+			"utterance_id": utteranceID,
+			"audio":        audioResponse,
+			"timestamp":    time.Now().UnixMilli(),
+			"latency_ms":   "< 300",
+			"text":         originalText,
 		},
 	}
 
@@ -350,6 +553,231 @@ func (c *Client) sendInterruption(errorResult *services.ErrorResult, originalTex
 	c.send <- responseData
 }
 
+// sendLateCorrection sends the errors the LLM found after the interruption
+// for an utterance went out. It runs outside the read loop, so it only uses
+// the session state in uc, and carries no audio, so the client can show it
+// without stopping the user again. Only the LLM's findings are new; the
+// rule findings merged with them were shown and counted with the utterance.
+func (c *Client) sendLateCorrection(uc utteranceContext, text string, errorResults []*services.ErrorResult) {
+	fresh := make([]*services.ErrorResult, 0, len(errorResults))
+	for _, errorResult := range errorResults {
+		if errorResult.Source == services.SourceLLM {
+			fresh = append(fresh, errorResult)
+		} else {
+			c.explainAgain(uc, errorResult)
+		}
+	}
+	c.explainAt(uc, fresh)
+	err := c.SendMessage("late_correction", map[string]interface{}{
+		"utterance_id": uc.utteranceID,
+		"session_id":   uc.sessionID,
+		"text":         text,
+		"error":        services.MostSevere(errorResults),
+		"errors":       errorResults,
+		"timestamp":    time.Now().UnixMilli(),
+	})
+	if err != nil {
+		log.Printf("Error sending late correction: %v", err)
+		return
+	}
+	c.recordShown(uc, fresh)
+}
+
+// utterance captures the session state an utterance is handled in. It must
+// be called on the read loop.
+func (c *Client) utterance(utteranceID string) utteranceContext {
+	return utteranceContext{
+		sessionID:   c.sessionID,
+		utteranceID: utteranceID,
+		depth:       c.depth(),
+		strictness:  c.interviewer.GetPersona(c.domain).StrictnessLevel,
+	}
+}
+
+// utteranceID returns the ID of the utterance a transcript belongs to: the
+// utterance_id the client sent, or else a number that moves on after each
+// final transcript, so interim and final transcripts of one utterance share it
+func (c *Client) utteranceID(payload map[string]interface{}, isFinal bool) string {
+	if id, ok := payload["utterance_id"].(string); ok && id != "" {
+		return id
+	}
+
+	id := fmt.Sprintf("%s-%d", c.sessionID, c.utteranceSeq+1)
+	if isFinal {
+		c.utteranceSeq++
+	}
+	return id
+}
+
+// handleDispute records that the user says a finding was not a mistake
+func (c *Client) handleDispute(payload map[string]interface{}) {
+	ruleID, _ := payload["rule_id"].(string)
+	utteranceID, _ := payload["utterance_id"].(string)
+	text, _ := payload["text"].(string)
+	matched, _ := payload["matched"].(string)
+	comment, _ := payload["comment"].(string)
+
+	if c.feedbackService == nil {
+		return
+	}
+
+	err := c.feedbackService.Dispute(services.Dispute{
+		UserID:      c.userID,
+		SessionID:   c.sessionID,
+		UtteranceID: utteranceID,
+		RuleID:      ruleID,
+		Text:        text,
+		Matched:     matched,
+		Comment:     comment,
+	})
+	if err != nil {
+		c.SendMessage("dispute_rejected", map[string]interface{}{
+			"rule_id":      ruleID,
+			"utterance_id": utteranceID,
+			"error":        err.Error(),
+		})
+		return
+	}
+
+	c.SendMessage("dispute_recorded", map[string]interface{}{
+		"rule_id":      ruleID,
+		"utterance_id": utteranceID,
+		"message":      "Thanks! We'll use this to improve our corrections.",
+	})
+}
+
+// recordShown counts the findings sent to the user for the rules' false-positive
+// rates and the session's grammar score
+func (c *Client) recordShown(uc utteranceContext, errorResults []*services.ErrorResult) {
+	if c.feedbackService != nil {
+		c.feedbackService.RecordShown(c.userID, errorResults)
+	}
+	if c.chunkAnalyzer != nil {
+		c.chunkAnalyzer.RecordErrors(uc.sessionID, uc.utteranceID, errorResults)
+	}
+}
+
+// analyzeFluency tracks the disfluencies in a final transcript and sends
+// the updated fluency metrics
+func (c *Client) analyzeFluency(transcript string) {
+	if c.fluencyAnalyzer == nil {
+		return
+	}
+	c.sendFluencyUpdate(c.fluencyAnalyzer.AnalyzeUtterance(c.sessionID, transcript))
+}
+
+// analyzePronunciation looks for words the user may be mispronouncing in a
+// final transcript, using the per-word confidences and timings in words if
+// the client sent Deepgram's, and sends the words it adds to the practise list
+func (c *Client) analyzePronunciation(utteranceID, transcript string, words interface{}) {
+	if c.pronunciation == nil {
+		return
+	}
+
+	added := c.pronunciation.AnalyzeUtterance(c.sessionID, utteranceID, transcript, transcriptWords(words))
+	if len(added) == 0 {
+		return
+	}
+
+	err := c.SendMessage("pronunciation_hint", map[string]interface{}{
+		"session_id":   c.sessionID,
+		"utterance_id": utteranceID,
+		"words":        added,
+		"timestamp":    time.Now().UnixMilli(),
+	})
+	if err != nil {
+		log.Printf("Error sending pronunciation hint: %v", err)
+	}
+}
+
+// analyzeSpeech adds a final transcript to the session's speech metrics and
+// sends them, at most every metricsUpdateInterval
+func (c *Client) analyzeSpeech(utteranceID, transcript string, words interface{}) {
+	if c.chunkAnalyzer == nil {
+		return
+	}
+
+	metrics := c.chunkAnalyzer.RecordUtterance(c.sessionID, utteranceID, transcript, transcriptWords(words))
+	if time.Since(c.lastMetricsUpdate) < metricsUpdateInterval {
+		return
+	}
+	c.lastMetricsUpdate = time.Now()
+
+	err := c.SendMessage("metrics_update", map[string]interface{}{
+		"session_id": c.sessionID,
+		"metrics":    metrics,
+		"timestamp":  time.Now().UnixMilli(),
+	})
+	if err != nil {
+		log.Printf("Error sending metrics update: %v", err)
+	}
+}
+
+// transcriptWords decodes the per-word timings and confidences a client
+// forwards from Deepgram, nil if there are none or they are malformed
+func transcriptWords(words interface{}) []services.TranscriptWord {
+	if words == nil {
+		return nil
+	}
+
+	var transcriptWords []services.TranscriptWord
+	data, _ := json.Marshal(words)
+	if err := json.Unmarshal(data, &transcriptWords); err != nil {
+		log.Printf("Ignoring malformed transcript words: %v", err)
+		return nil
+	}
+	return transcriptWords
+}
+
+// analyzeVocabulary counts the overused basic words in a final transcript
+// and sends a tip for each word that has just been said too often
+func (c *Client) analyzeVocabulary(utteranceID, transcript string) {
+	if c.chunkAnalyzer == nil {
+		return
+	}
+
+	for _, tip := range c.chunkAnalyzer.TrackVocabulary(c.sessionID, transcript) {
+		err := c.SendMessage("vocabulary_tip", map[string]interface{}{
+			"session_id":   c.sessionID,
+			"utterance_id": utteranceID,
+			"tip":          tip,
+			"timestamp":    time.Now().UnixMilli(),
+		})
+		if err != nil {
+			log.Printf("Error sending vocabulary tip: %v", err)
+		}
+	}
+}
+
+// expectRepeat has the next utterance compared with the corrected sentence
+// the user is asked to repeat, for mispronounced words and fixed errors
+func (c *Client) expectRepeat(uc utteranceContext, errorResult *services.ErrorResult, errorResults []*services.ErrorResult) {
+	if c.pronunciation != nil {
+		c.pronunciation.ExpectRepeat(uc.sessionID, uc.utteranceID, errorResult)
+	}
+	if c.chunkAnalyzer != nil {
+		c.chunkAnalyzer.ExpectFixes(uc.sessionID, uc.utteranceID, errorResults)
+	}
+}
+
+// sendFluencyUpdate sends live fluency metrics. Unlike an interruption it
+// carries no audio, so the client can show it without stopping the user.
+func (c *Client) sendFluencyUpdate(update *services.FluencyUpdate) {
+	if update == nil {
+		return
+	}
+
+	err := c.SendMessage("fluency_update", map[string]interface{}{
+		"session_id": c.sessionID,
+		"events":     update.Events,
+		"metrics":    update.Metrics,
+		"timestamp":  time.Now().UnixMilli(),
+	})
+	if err != nil {
+		log.Printf("Error sending fluency update: %v", err)
+	}
+}
+
 // SendMessage sends a message to the client
 func (c *Client) SendMessage(msgType string, payload map[string]interface{}) error {
 	msg := Message{
@@ -362,6 +790,12 @@ func (c *Client) SendMessage(msgType string, payload map[string]interface{}) err
 		return fmt.Errorf("error marshaling message: %w", err)
 	}
 
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+	if c.closed {
+		return fmt.Errorf("client connection closed")
+	}
+
 	select {
 	case c.send <- data:
 		return nil
@@ -369,3 +803,70 @@ func (c *Client) SendMessage(msgType string, payload map[string]interface{}) err
 		return fmt.Errorf("client send buffer full")
 	}
 }
+
+// closeSend closes the outbound channel; messages sent afterwards are dropped
+func (c *Client) closeSend() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
+}
+
+// style returns the explanation style of the session: the one chosen in
+// start_session, or the user's saved style
+func (c *Client) style() string {
+	if c.explanationStyle == "" {
+		c.explanationStyle = services.StyleNative
+		if c.preferences != nil {
+			c.explanationStyle = c.preferences.ExplanationStyle(c.ctx, c.userID)
+		}
+	}
+	return c.explanationStyle
+}
+
+// depth returns the explanation depth of the session: the one chosen in
+// start_session, or the user's saved depth, which may be DepthAuto. The
+// saved depth is read once per session.
+func (c *Client) depth() string {
+	if c.explanationDepth == "" {
+		c.explanationDepth = services.DepthAuto
+		if c.preferences != nil {
+			c.explanationDepth = c.preferences.ExplanationDepth(c.ctx, c.userID)
+		}
+	}
+	return c.explanationDepth
+}
+
+// explainAt sets how deeply each finding sent to the user is explained. At
+// DepthAuto that follows the strictness of the session's persona and gets
+// deeper for rules the user keeps getting wrong.
+func (c *Client) explainAt(uc utteranceContext, errorResults []*services.ErrorResult) {
+	for _, errorResult := range errorResults {
+		explainedBefore := 0
+		if c.preferences != nil {
+			explainedBefore = c.preferences.RecordExplained(c.userID, errorResult.RuleID)
+		}
+		errorResult.SetDepth(services.ChooseDepth(uc.depth, uc.strictness, explainedBefore))
+	}
+}
+
+// explainAgain explains a finding that was already sent at the depth it was
+// sent at, without counting it as explained again
+func (c *Client) explainAgain(uc utteranceContext, errorResult *services.ErrorResult) {
+	explainedBefore := 0
+	if c.preferences != nil {
+		explainedBefore = max(c.preferences.Explained(c.userID, errorResult.RuleID)-1, 0)
+	}
+	errorResult.SetDepth(services.ChooseDepth(uc.depth, uc.strictness, explainedBefore))
+}
+
+// addWarning adds a warning to a response payload, after any it has
+func addWarning(payload map[string]interface{}, warning string) {
+	if existing, ok := payload["warning"].(string); ok && existing != "" {
+		warning = existing + "; " + warning
+	}
+	payload["warning"] = warning
+}
@@ -13,22 +13,34 @@ type Handler struct {
 	grammarDetector *services.GrammarDetector
 	deepgramService *services.DeepgramService
 	chunkAnalyzer   *services.ChunkAnalyzer
+	fluencyAnalyzer *services.FluencyAnalyzer
+	pronunciation   *services.PronunciationAnalyzer
+	feedbackService *services.FeedbackService
+	preferences     *services.UserPreferences
+	interviewer     *services.InterviewerService
+	sessions        *services.SessionStore
 }
 
 // NewHandler creates a new WebSocket handler
-func NewHandler(hub *Hub, grammarDetector *services.GrammarDetector, deepgramService *services.DeepgramService, chunkAnalyzer *services.ChunkAnalyzer) *Handler {
+func NewHandler(hub *Hub, grammarDetector *services.GrammarDetector, deepgramService *services.DeepgramService, chunkAnalyzer *services.ChunkAnalyzer, fluencyAnalyzer *services.FluencyAnalyzer, pronunciation *services.PronunciationAnalyzer, feedbackService *services.FeedbackService, preferences *services.UserPreferences, interviewer *services.InterviewerService, sessions *services.SessionStore) *Handler {
 	return &Handler{
 		hub:             hub,
 		grammarDetector: grammarDetector,
 		deepgramService: deepgramService,
 		chunkAnalyzer:   chunkAnalyzer,
+		fluencyAnalyzer: fluencyAnalyzer,
+		pronunciation:   pronunciation,
+		feedbackService: feedbackService,
+		preferences:     preferences,
+		interviewer:     interviewer,
+		sessions:        sessions,
 	}
 }
 
 // ServeFiberWs handles Fiber WebSocket connections
 func (h *Handler) ServeFiberWs(conn *fiberws.Conn, userID string, nativeLanguage string) {
 	// Create new client with Fiber WebSocket connection
-	client := NewFiberClient(h.hub, conn, userID, nativeLanguage, h.grammarDetector, h.deepgramService, h.chunkAnalyzer)
+	client := NewFiberClient(h.hub, conn, userID, nativeLanguage, h.grammarDetector, h.deepgramService, h.chunkAnalyzer, h.fluencyAnalyzer, h.pronunciation, h.feedbackService, h.preferences, h.interviewer, h.sessions)
 	client.hub.register <- client
 
 	// Start client goroutines
@@ -0,0 +1,29 @@
+-- Disputed grammar findings ("that wasn't a mistake") and per-rule feedback
+
+-- One row per dispute. user_id is the id the client connected with, which
+-- need not be a row of users (e.g. "anonymous").
+CREATE TABLE rule_disputes (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id TEXT NOT NULL,
+  session_id TEXT,
+  utterance_id TEXT,
+  rule_id TEXT NOT NULL,
+  text TEXT NOT NULL,
+  matched TEXT,
+  comment TEXT,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Running totals per rule, rewritten by the backend's feedback job
+CREATE TABLE rule_feedback (
+  rule_id TEXT PRIMARY KEY,
+  shown INT NOT NULL DEFAULT 0,
+  disputed INT NOT NULL DEFAULT 0,
+  false_positive_rate DECIMAL(5,4) NOT NULL DEFAULT 0,
+  confidence DECIMAL(5,4) NOT NULL DEFAULT 0, -- Lowered confidence, 0 if unchanged
+  disabled BOOLEAN NOT NULL DEFAULT FALSE,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_rule_disputes_rule_id ON rule_disputes(rule_id);
+CREATE INDEX idx_rule_disputes_created_at ON rule_disputes(created_at DESC);
@@ -0,0 +1,20 @@
+-- LLM translations of explanations the message catalogue lacks
+
+-- One row per English explanation, language and style. language and style
+-- are lowercase. Admins edit and approve rows; approved translations of a
+-- rule's description can be exported into the catalogue.
+CREATE TABLE translation_cache (
+  english TEXT NOT NULL,
+  language TEXT NOT NULL,
+  style TEXT NOT NULL DEFAULT 'native',
+  rule_id TEXT NOT NULL,
+  translation TEXT NOT NULL,
+  approved BOOLEAN NOT NULL DEFAULT FALSE,
+  edited BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  PRIMARY KEY (english, language, style)
+);
+
+CREATE INDEX idx_translation_cache_language ON translation_cache(language, approved);
+CREATE INDEX idx_translation_cache_updated_at ON translation_cache(updated_at DESC);
@@ -0,0 +1,9 @@
+-- How each user wants grammar explanations written
+
+-- native: the native language in its own script; romanized: the native
+-- language in Latin script; code_mixed: the native language mixed with
+-- English in Latin script, e.g. Hinglish. A session can choose another
+-- style for itself.
+ALTER TABLE users
+  ADD COLUMN explanation_style TEXT NOT NULL DEFAULT 'native'
+  CHECK (explanation_style IN ('native', 'romanized', 'code_mixed'));
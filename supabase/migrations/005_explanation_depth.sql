@@ -0,0 +1,10 @@
+-- How deeply each user wants grammar errors explained
+
+-- terse: the correction only; rule: the correction and a one-line rule;
+-- lesson: the rule, the grammar behind it and two example sentences;
+-- auto: chosen from the interviewer persona's strictness, deeper for rules
+-- the user keeps getting wrong. A session can choose another depth for
+-- itself.
+ALTER TABLE users
+  ADD COLUMN explanation_depth TEXT NOT NULL DEFAULT 'auto'
+  CHECK (explanation_depth IN ('auto', 'terse', 'rule', 'lesson'));
@@ -0,0 +1,13 @@
+-- How each session's grammar_score was reached
+
+-- The backend saves sessions when they end, with grammar_score from 0 to
+-- 100: errors weighted by severity and category, those the user fixed when
+-- repeating the corrected sentence at a quarter, per 100 words spoken (at
+-- least 50). The breakdown holds the word and error counts and what each
+-- category of errors took off the score.
+ALTER TABLE sessions
+  ADD COLUMN grammar_breakdown JSONB;
+
+ALTER TABLE sessions
+  ADD CONSTRAINT sessions_grammar_score_range
+  CHECK (grammar_score BETWEEN 0 AND 100);
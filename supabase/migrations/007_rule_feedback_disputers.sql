@@ -0,0 +1,7 @@
+-- How many different users disputed each rule
+
+-- A rule is only demoted or switched off once five different users have
+-- disputed its findings, so one user disputing again and again can't turn
+-- it off. The users are counted from rule_disputes when the backend starts.
+ALTER TABLE rule_feedback
+  ADD COLUMN disputers INT NOT NULL DEFAULT 0;